@@ -9,16 +9,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/limits"
 	"github.com/euandresimoes/visualdb-go.git/internal/infra/middlewares"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
 	"github.com/euandresimoes/visualdb-go.git/internal/modules/columns"
+	"github.com/euandresimoes/visualdb-go.git/internal/modules/history"
+	"github.com/euandresimoes/visualdb-go.git/internal/modules/notify"
 	"github.com/euandresimoes/visualdb-go.git/internal/modules/query"
 	"github.com/euandresimoes/visualdb-go.git/internal/modules/rows"
+	"github.com/euandresimoes/visualdb-go.git/internal/modules/savedqueries"
 	"github.com/euandresimoes/visualdb-go.git/internal/modules/schemas"
 	"github.com/euandresimoes/visualdb-go.git/internal/modules/tables"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.etcd.io/bbolt"
 )
 
 func (api *ApiConfig) Init() {
@@ -29,7 +36,7 @@ func (api *ApiConfig) Init() {
 		AllowedOrigins:   []string{"https://*", "http://*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", activity.IDHeader},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -40,10 +47,11 @@ func (api *ApiConfig) Init() {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	// Set a timeout value on the request context (ctx), that will signal
-	// through ctx.Done() that the request has timed out and further
-	// processing should be stopped.
-	r.Use(middleware.Timeout(60 * time.Second))
+	// There is no request timeout for the whole API: queries are bounded by
+	// the statement timeout in api.Limits instead, so long exports and
+	// streams still work. Routes only reading metadata get the default
+	// timeout as a request timeout.
+	metadataTimeout := middlewares.Timeout(api.Limits.DefaultTimeoutMs)
 
 	// API Routes
 	r.Route("/api", func(r chi.Router) {
@@ -53,37 +61,64 @@ func (api *ApiConfig) Init() {
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(map[string]any{
-				"status":  http.StatusOK,
-				"message": "pong",
-				"version": api.Version,
-				"data":    api.DBConfig,
+				"status":    http.StatusOK,
+				"message":   "pong",
+				"version":   api.Version,
+				"read_only": api.ReadOnly,
+				"data":      api.DBConfig,
 			})
 		})
 
+		tracker := activity.NewTracker()
+
 		schemasRepository := schemas.NewRepository(api.DBPool, api.DBConfig.DBType)
 		schemasService := schemas.NewService(schemasRepository)
 		schemasHandler := schemas.NewHandler(schemasService)
-		r.Mount("/schemas", schemasHandler)
+		r.With(metadataTimeout).Mount("/schemas", schemasHandler)
 
 		tablesRepository := tables.NewRepository(api.DBPool, api.DBConfig.DBType)
 		tablesService := tables.NewService(tablesRepository)
 		tablesHandler := tables.NewHandler(tablesService)
-		r.Mount("/tables", tablesHandler)
+		r.With(metadataTimeout).Mount("/tables", tablesHandler)
 
 		columnsRepository := columns.NewRepository(api.DBPool, api.DBConfig.DBType)
 		columnsService := columns.NewService(columnsRepository)
 		columnsHandler := columns.NewHandler(columnsService)
-		r.Mount("/columns", columnsHandler)
+		r.With(metadataTimeout).Mount("/columns", columnsHandler)
 
-		rowsRepository := rows.NewRepository(api.DBPool, api.DBConfig.DBType)
+		rowsRepository := rows.NewRepository(api.DBPool, api.DBConfig.DBType, tracker)
 		rowsService := rows.NewService(rowsRepository)
-		rowsHandler := rows.NewHandler(rowsService)
-		r.Mount("/rows", rowsHandler)
-
-		queryRepository := query.NewRepository(api.DBPool, api.DBConfig.DBType)
-		queryService := query.NewService(queryRepository)
-		queryHandler := query.NewHandler(queryService)
-		r.Mount("/query", queryHandler)
+		rowsHandler := rows.NewHandler(rowsService, api.ReadOnly, api.Limits)
+		r.With(middlewares.QueryID).Mount("/rows", rowsHandler)
+
+		historyRepository, err := history.NewRepository(api.Store)
+		if err != nil {
+			log.Fatalf("Error while opening query history %v\n", err)
+		}
+		historyService := history.NewService(historyRepository)
+
+		// Sessions hold on to their connection, so they may take at most
+		// half of the pool, but always at least one connection: a limit of
+		// zero would mean no limit.
+		sessionManager := sessions.NewManager(api.SessionIdleTimeout, max(1, int(api.DBPool.Config().MaxConns)/2))
+
+		queryRepository := query.NewRepository(api.DBPool, api.DBConfig.DBType, tracker, sessionManager, api.ReadOnly)
+		queryService := query.NewService(queryRepository, historyService)
+		queryHandler := query.NewHandler(queryService, api.Limits)
+		r.With(middlewares.QueryID).Mount("/query", queryHandler)
+
+		savedQueriesRepository, err := savedqueries.NewRepository(api.Store)
+		if err != nil {
+			log.Fatalf("Error while opening saved queries %v\n", err)
+		}
+		savedQueriesService := savedqueries.NewService(savedQueriesRepository)
+		savedQueriesHandler := savedqueries.NewHandler(savedQueriesService)
+		r.Mount("/saved-queries", savedQueriesHandler)
+
+		notifyRepository := notify.NewRepository(api.DBPool, api.DBConfig.DBType, api.ListenMaxSubscribers)
+		notifyService := notify.NewService(notifyRepository)
+		notifyHandler := notify.NewHandler(notifyService, api.ReadOnly)
+		r.Mount("/notify", notifyHandler)
 	})
 
 	// Frontend - SPA
@@ -119,6 +154,17 @@ type ApiConfig struct {
 	ApiPort  string
 	DBPool   *pgxpool.Pool
 	DBConfig *DBConfig
+	ReadOnly bool
+	Limits   limits.Config
+	Store    *bbolt.DB
+
+	// SessionIdleTimeout is how long a query editor session may stay idle
+	// before its transaction is rolled back and its connection closed.
+	SessionIdleTimeout time.Duration
+
+	// ListenMaxSubscribers caps the WebSockets listening for notifications
+	// at once. Zero means no limit.
+	ListenMaxSubscribers int
 }
 
 type DBConfig struct {
@@ -3,8 +3,12 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/infra/database"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/limits"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/localstore"
 )
 
 func main() {
@@ -26,6 +30,47 @@ func main() {
 
 	log.Printf("envs: %v\n", envs)
 
+	readOnly := false
+	if val := os.Getenv("READ_ONLY"); val != "" {
+		var err error
+		if readOnly, err = strconv.ParseBool(val); err != nil {
+			log.Fatalf("Invalid env READ_ONLY: %s", val)
+		}
+	}
+
+	// Defaults apply when a query doesn't set its own limits, ceilings cap
+	// the ones it does set. Streamed results and exports only get the limits
+	// they ask for, and rows are only capped if STREAM_MAX_ROWS_CEILING is
+	// set. Zero means no limit.
+	queryLimits := limits.Config{
+		DefaultTimeoutMs: intEnv("QUERY_TIMEOUT_MS", 60_000),
+		MaxTimeoutMs:     intEnv("QUERY_TIMEOUT_CEILING_MS", 600_000),
+		DefaultMaxRows:   intEnv("QUERY_MAX_ROWS", 10_000),
+		MaxRows:          intEnv("QUERY_MAX_ROWS_CEILING", 100_000),
+		StreamMaxRows:    intEnv("STREAM_MAX_ROWS_CEILING", 0),
+	}
+
+	// Query editor sessions left idle this long are rolled back and closed.
+	sessionIdleTimeout := time.Duration(intEnv("SESSION_IDLE_TIMEOUT_MS", 300_000)) * time.Millisecond
+	if sessionIdleTimeout <= 0 {
+		log.Fatalf("Invalid env SESSION_IDLE_TIMEOUT_MS: must be positive")
+	}
+
+	// Subscribers to notifications share one connection, but each holds a
+	// WebSocket and a buffer of notifications.
+	listenMaxSubscribers := intEnv("LISTEN_MAX_SUBSCRIBERS", 100)
+
+	dataDir := os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "data"
+	}
+
+	store, err := localstore.Open(dataDir)
+	if err != nil {
+		log.Fatalf("Error while opening local store %v\n", err)
+	}
+	defer store.Close()
+
 	pool, err := database.NewPool(database.DBConnConfig{
 		Type:     envs["DB_TYPE"],
 		User:     envs["DB_USER"],
@@ -34,15 +79,23 @@ func main() {
 		Port:     envs["DB_PORT"],
 		Db:       envs["DB_NAME"],
 		SSLMode:  envs["SSL_MODE"],
+		ReadOnly: readOnly,
 	})
 	if err != nil {
 		log.Fatalf("Error while creating connection pool %v\n", err)
 	}
 
 	api := &ApiConfig{
-		Version: "v2.0",
-		ApiPort: ":23806",
-		DBPool:  pool,
+		Version:            "v2.0",
+		ApiPort:            ":23806",
+		DBPool:             pool,
+		ReadOnly:           readOnly,
+		Limits:             queryLimits,
+		Store:              store,
+		SessionIdleTimeout: sessionIdleTimeout,
+
+		ListenMaxSubscribers: listenMaxSubscribers,
+
 		DBConfig: &DBConfig{
 			DBHost: envs["DB_HOST"],
 			DBPort: envs["DB_PORT"],
@@ -54,3 +107,17 @@ func main() {
 
 	api.Init()
 }
+
+func intEnv(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid env %s: %s", key, val)
+	}
+
+	return n
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ApplyChangeset applies the changes in order in a single transaction,
+// committed only when every change succeeds. A change fails like the
+// matching single edit would; the changes after it are skipped and the
+// whole changeset is rolled back.
+func ApplyChangeset(ctx context.Context, db *pgxpool.Pool, changes []models.RowChange, timeoutMs int) (*models.ApiResponse, error) {
+	// The columns of each table inserted into or updated, looked up once.
+	tables := make(map[string]*tableInfo)
+	for _, change := range changes {
+		name := pgx.Identifier{change.Schema, change.Table}.Sanitize()
+		if _, ok := tables[name]; ok || change.Op == models.ChangeDelete {
+			continue
+		}
+
+		info, err := tableColumns(ctx, db, change.Schema, change.Table)
+		if err != nil {
+			return nil, err
+		}
+		tables[name] = info
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result := &models.ChangesetResult{Changes: make([]models.ChangeResult, len(changes))}
+
+	for i, change := range changes {
+		res := &result.Changes[i]
+		res.Op, res.Schema, res.Table = change.Op, change.Schema, change.Table
+
+		if result.Failed > 0 {
+			res.Skipped = true
+			continue
+		}
+
+		written, err := applyChange(ctx, tx.Conn(), change, tables)
+		if err != nil {
+			res.Error = err.Error()
+			res.ErrorDetails = pgerror.Details(err)
+			var conflict *models.RowConflictError
+			if errors.As(err, &conflict) {
+				res.Current = conflict.Row
+			}
+			result.Failed++
+			continue
+		}
+
+		res.RowsAffected = written.RowsAffected
+		res.Row = written.Row
+	}
+
+	if result.Failed > 0 {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		result.RolledBack = true
+	} else if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	message := "success"
+	if result.RolledBack {
+		message = "transaction rolled back"
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: message,
+		Data:    result,
+	}, nil
+}
+
+func applyChange(ctx context.Context, conn *pgx.Conn, change models.RowChange, tables map[string]*tableInfo) (*models.RowWrite, error) {
+	info := tables[pgx.Identifier{change.Schema, change.Table}.Sanitize()]
+
+	switch change.Op {
+	case models.ChangeInsert:
+		return insertRow(ctx, conn, change.Schema, change.Table, info, change.Data, models.InsertOptions{})
+	case models.ChangeUpdate:
+		return updateRow(ctx, conn, change.Schema, change.Table, info, change.Key, change.Version, change.Data)
+	case models.ChangeDelete:
+		qr, err := deleteRow(ctx, conn, change.Schema, change.Table, change.Key, change.Version)
+		if err != nil {
+			return nil, err
+		}
+		return &models.RowWrite{RowsAffected: qr.RowsAffected}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown change %q", models.ErrInvalidRow, change.Op)
+	}
+}
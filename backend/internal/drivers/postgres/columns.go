@@ -8,14 +8,38 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func GetColumns(db *pgxpool.Pool, schema string, table string) (*models.ApiResponse, error) {
+func GetColumns(ctx context.Context, db *pgxpool.Pool, schema string, table string) (*models.ApiResponse, error) {
+	info, err := readColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.columns) == 0 {
+		return &models.ApiResponse{Status: http.StatusNoContent, Message: "no columns found"}, nil
+	}
+
+	return &models.ApiResponse{Status: http.StatusOK, Message: "success", Data: info.columns}, nil
+}
+
+// tableInfo holds the columns of a table and its row key (see keyColumns).
+type tableInfo struct {
+	columns []models.ColumnModel
+	rowKey  []string
+}
+
+// readColumns reads the columns of the table, none when it does not exist.
+// The row key comes with them.
+func readColumns(ctx context.Context, db *pgxpool.Pool, schema string, table string) (*tableInfo, error) {
 	query := `
+		WITH row_key AS (` + rowKeyQuery + `)
 		SELECT
 			c.column_name,
 			c.data_type,
 			c.is_nullable,
 			c.column_default,
-			CASE WHEN tc.constraint_type = 'PRIMARY KEY' THEN true ELSE false END AS is_primary_key
+			CASE WHEN tc.constraint_type = 'PRIMARY KEY' THEN true ELSE false END AS is_primary_key,
+			COALESCE(c.column_name::text = ANY ((SELECT columns FROM row_key)), false) AS is_row_key,
+			(SELECT columns FROM row_key) AS row_key
 		FROM information_schema.columns c
 		LEFT JOIN information_schema.key_column_usage kcu
 			ON c.table_name = kcu.table_name
@@ -32,7 +56,7 @@ func GetColumns(db *pgxpool.Pool, schema string, table string) (*models.ApiRespo
 	`
 
 	rows, err := db.Query(
-		context.Background(),
+		ctx,
 		query,
 		schema, table,
 	)
@@ -41,33 +65,31 @@ func GetColumns(db *pgxpool.Pool, schema string, table string) (*models.ApiRespo
 	}
 	defer rows.Close()
 
-	columnsList := []models.ColumnModel{}
+	info := &tableInfo{columns: []models.ColumnModel{}}
 	for rows.Next() {
 		var columnName string
 		var dataType string
 		var isNullable *string
 		var columnDefault *string
 		var isPrimaryKey bool
-		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &isPrimaryKey); err != nil {
+		var isRowKey bool
+		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &isPrimaryKey, &isRowKey, &info.rowKey); err != nil {
 			return nil, err
 		}
 
-		columnsList = append(columnsList, models.ColumnModel{
+		info.columns = append(info.columns, models.ColumnModel{
 			Name:          columnName,
 			DataType:      dataType,
 			IsNullable:    isNullable,
 			ColumnDefault: columnDefault,
 			IsPrimaryKey:  isPrimaryKey,
+			IsRowKey:      isRowKey,
 		})
 	}
 
-	if len(columnsList) == 0 {
-		return &models.ApiResponse{Status: http.StatusNoContent, Message: "no columns found"}, nil
-	}
-
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
 
-	return &models.ApiResponse{Status: http.StatusOK, Message: "success", Data: columnsList}, nil
+	return info, nil
 }
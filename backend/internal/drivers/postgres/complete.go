@@ -0,0 +1,689 @@
+package postgres
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/cache"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxCompletions caps how many suggestions are returned at once.
+const maxCompletions = 200
+
+var completionKeywords = []string{
+	"select", "from", "where", "join", "left", "right", "inner", "outer", "full", "cross", "natural",
+	"lateral", "on", "using", "group", "by", "order", "having", "limit", "offset", "window",
+	"partition", "over", "filter", "insert", "into", "values", "update", "set", "delete",
+	"returning", "conflict", "do", "nothing", "with", "recursive", "as", "distinct", "all",
+	"union", "except", "intersect", "and", "or", "not", "null", "is", "in", "exists", "between",
+	"like", "ilike", "similar", "case", "when", "then", "else", "end", "cast", "asc", "desc",
+	"nulls", "first", "last", "true", "false", "default", "create", "alter", "drop", "table",
+	"view", "index", "only", "truncate", "begin", "commit", "rollback", "explain", "analyze",
+}
+
+// relationKeywords are followed by a table name.
+var relationKeywords = []string{"from", "join", "into", "update", "table", "truncate"}
+
+// clauseKeywords start the clauses that decide what is expected at the
+// cursor.
+var clauseKeywords = []string{
+	"select", "from", "join", "where", "on", "group", "order", "having", "set", "returning",
+	"values", "into", "update", "limit", "offset", "using",
+}
+
+// columnClauses are the clauses in which column names are expected.
+var columnClauses = []string{"select", "where", "on", "group", "order", "having", "set", "returning", "using"}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokQuoted
+	tokString
+	tokNumber
+	tokPunct
+	tokComment
+)
+
+type sqlToken struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+	// closed is false for strings, quoted identifiers and comments that run
+	// to the end of their line or of the script.
+	closed bool
+}
+
+type tableRef struct {
+	schema string
+	table  string
+	alias  string
+}
+
+// completer reads the catalog through a cache, so completing while typing
+// doesn't query the server on every keystroke.
+type completer struct {
+	ctx     context.Context
+	db      *pgxpool.Pool
+	catalog *cache.Cache
+}
+
+// Complete suggests what may be typed at cursor, a character offset into
+// sql: tables after FROM and similar keywords, the columns of the tables
+// used in the statement, functions and keywords.
+func Complete(ctx context.Context, db *pgxpool.Pool, catalog *cache.Cache, sql string, cursor int) (*models.ApiResponse, error) {
+	c := completer{ctx: ctx, db: db, catalog: catalog}
+
+	pos := byteOffset(sql, cursor)
+	tokens := tokenize(sql)
+
+	result := &models.CompletionResult{Suggestions: []models.Completion{}}
+	result.From = utf8.RuneCountInString(sql[:pos])
+	result.To = result.From
+
+	for _, tok := range tokens {
+		if (tok.kind == tokString || tok.kind == tokComment) && tok.start < pos && (pos < tok.end || !tok.closed) {
+			return completionResponse(result), nil
+		}
+	}
+
+	stmt := statementAt(tokens, pos)
+
+	// The word being typed, if any, is the prefix suggestions must match.
+	prefix := ""
+	before := stmt
+	for i, tok := range stmt {
+		if tok.end < pos || (tok.end == pos && !isIdent(tok)) {
+			continue
+		}
+
+		before = stmt[:i]
+		if tok.start < pos && isIdent(tok) {
+			prefix = strings.Trim(sql[tok.start:pos], `"`)
+			result.From = utf8.RuneCountInString(sql[:tok.start])
+			result.To = utf8.RuneCountInString(sql[:tok.end])
+		}
+		break
+	}
+
+	qualifier := ""
+	if n := len(before); n >= 2 && isPunct(before[n-1], ".") && isIdent(before[n-2]) {
+		qualifier = identName(before[n-2])
+		before = before[:n-2]
+	}
+
+	refs := tableRefs(stmt)
+
+	var suggestions []models.Completion
+	var err error
+
+	switch {
+	case qualifier != "":
+		suggestions, err = c.qualified(qualifier, refs)
+
+	case expectsRelation(before):
+		suggestions, err = c.relations()
+
+	case expectsColumn(before):
+		suggestions, err = c.columnsOf(refs, before)
+		if err == nil {
+			var functions []models.Completion
+			functions, err = c.functionCompletions()
+			suggestions = append(suggestions, keywordCompletions()...)
+			suggestions = append(suggestions, functions...)
+		}
+
+	default:
+		suggestions = keywordCompletions()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, s := range suggestions {
+		name := strings.ToLower(strings.Trim(s.Label, `"`))
+		if !strings.HasPrefix(name, strings.ToLower(prefix)) || seen[s.Kind+"\x00"+s.Label] {
+			continue
+		}
+		seen[s.Kind+"\x00"+s.Label] = true
+
+		result.Suggestions = append(result.Suggestions, s)
+		if len(result.Suggestions) == maxCompletions {
+			break
+		}
+	}
+
+	return completionResponse(result), nil
+}
+
+func completionResponse(result *models.CompletionResult) *models.ApiResponse {
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    result,
+	}
+}
+
+// qualified completes the name after "qualifier.": the columns of a table or
+// alias used in the statement, or the tables of a schema.
+func (c completer) qualified(qualifier string, refs []tableRef) ([]models.Completion, error) {
+	for _, ref := range refs {
+		if ref.alias == qualifier || (ref.alias == "" && ref.table == qualifier) {
+			return c.columnsOf([]tableRef{ref}, nil)
+		}
+	}
+
+	schemas, err := c.schemas()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(schemas, qualifier) {
+		return nil, nil
+	}
+
+	tables, err := c.tables(qualifier)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]models.Completion, 0, len(tables))
+	for _, table := range tables {
+		suggestions = append(suggestions, models.Completion{Label: quoteIdent(table), Kind: models.CompletionTable, Detail: qualifier})
+	}
+
+	return suggestions, nil
+}
+
+// relations lists the tables of the schemas in the search path, then every
+// schema.
+func (c completer) relations() ([]models.Completion, error) {
+	path, err := c.searchPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []models.Completion
+	for _, schema := range path {
+		tables, err := c.tables(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range tables {
+			suggestions = append(suggestions, models.Completion{Label: quoteIdent(table), Kind: models.CompletionTable, Detail: schema})
+		}
+	}
+
+	schemas, err := c.schemas()
+	if err != nil {
+		return nil, err
+	}
+	for _, schema := range schemas {
+		suggestions = append(suggestions, models.Completion{Label: quoteIdent(schema), Kind: models.CompletionSchema})
+	}
+
+	return suggestions, nil
+}
+
+// columnsOf lists the columns of refs. For INSERT INTO t (...), only the
+// target table is used.
+func (c completer) columnsOf(refs []tableRef, before []sqlToken) ([]models.Completion, error) {
+	if clauseOf(before) == "into" && len(refs) > 0 {
+		refs = refs[:1]
+	}
+
+	var suggestions []models.Completion
+	for _, ref := range refs {
+		schema, err := c.schemaOf(ref)
+		if err != nil {
+			return nil, err
+		}
+		if schema == "" {
+			continue
+		}
+
+		columns, err := c.columns(schema, ref.table)
+		if err != nil {
+			return nil, err
+		}
+		for _, col := range columns {
+			suggestions = append(suggestions, models.Completion{Label: quoteIdent(col.Name), Kind: models.CompletionColumn, Detail: col.DataType})
+		}
+	}
+
+	return suggestions, nil
+}
+
+// schemaOf returns the schema of a table: the one it was qualified with, or
+// the first schema of the search path that has it.
+func (c completer) schemaOf(ref tableRef) (string, error) {
+	if ref.schema != "" {
+		return ref.schema, nil
+	}
+
+	path, err := c.searchPath()
+	if err != nil {
+		return "", err
+	}
+
+	for _, schema := range path {
+		tables, err := c.tables(schema)
+		if err != nil {
+			return "", err
+		}
+		if slices.Contains(tables, ref.table) {
+			return schema, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (c completer) functionCompletions() ([]models.Completion, error) {
+	functions, err := c.functions()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]models.Completion, 0, len(functions))
+	for _, name := range functions {
+		suggestions = append(suggestions, models.Completion{Label: quoteIdent(name), Kind: models.CompletionFunction})
+	}
+
+	return suggestions, nil
+}
+
+func keywordCompletions() []models.Completion {
+	suggestions := make([]models.Completion, len(completionKeywords))
+	for i, kw := range completionKeywords {
+		suggestions[i] = models.Completion{Label: strings.ToUpper(kw), Kind: models.CompletionKeyword}
+	}
+	return suggestions
+}
+
+func (c completer) schemas() ([]string, error) {
+	return c.names("schemas", func() (*models.ApiResponse, error) {
+		return GetSchemas(c.ctx, c.db)
+	})
+}
+
+func (c completer) tables(schema string) ([]string, error) {
+	return c.names("tables:"+schema, func() (*models.ApiResponse, error) {
+		return GetTables(c.ctx, c.db, schema)
+	})
+}
+
+func (c completer) functions() ([]string, error) {
+	return c.names("functions", func() (*models.ApiResponse, error) {
+		return GetFunctions(c.ctx, c.db)
+	})
+}
+
+func (c completer) columns(schema string, table string) ([]models.ColumnModel, error) {
+	value, err := c.catalog.Get("columns:"+schema+"."+table, func() (any, error) {
+		res, err := GetColumns(c.ctx, c.db, schema, table)
+		if err != nil {
+			return nil, err
+		}
+		columns, _ := res.Data.([]models.ColumnModel)
+		return columns, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]models.ColumnModel), nil
+}
+
+func (c completer) searchPath() ([]string, error) {
+	value, err := c.catalog.Get("search_path", func() (any, error) {
+		var path []string
+		err := c.db.QueryRow(c.ctx, `SELECT current_schemas(false)`).Scan(&path)
+		return path, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+// names caches the list of names returned by one of the catalog queries. An
+// empty list comes back as a 204 response without data.
+func (c completer) names(key string, load func() (*models.ApiResponse, error)) ([]string, error) {
+	value, err := c.catalog.Get(key, func() (any, error) {
+		res, err := load()
+		if err != nil {
+			return nil, err
+		}
+		names, _ := res.Data.([]string)
+		return names, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+func expectsRelation(before []sqlToken) bool {
+	if len(before) == 0 {
+		return false
+	}
+
+	prev := before[len(before)-1]
+	if prev.kind == tokWord && slices.Contains(relationKeywords, strings.ToLower(prev.text)) {
+		return true
+	}
+
+	return isPunct(prev, ",") && clauseOf(before) == "from"
+}
+
+func expectsColumn(before []sqlToken) bool {
+	if len(before) == 0 {
+		return false
+	}
+
+	clause := clauseOf(before)
+	if slices.Contains(columnClauses, clause) {
+		return true
+	}
+
+	// The column list of INSERT INTO t (...).
+	prev := before[len(before)-1]
+	return clause == "into" && (isPunct(prev, "(") || isPunct(prev, ","))
+}
+
+// clauseOf returns the clause keyword governing the end of tokens, skipping
+// parenthesized groups that are already closed.
+func clauseOf(tokens []sqlToken) string {
+	depth := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		tok := tokens[i]
+
+		switch {
+		case isPunct(tok, ")"):
+			depth++
+		case isPunct(tok, "("):
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && tok.kind == tokWord:
+			if kw := strings.ToLower(tok.text); slices.Contains(clauseKeywords, kw) {
+				return kw
+			}
+		}
+	}
+
+	return ""
+}
+
+// tableRefs collects the tables named after FROM, JOIN, UPDATE and INTO in a
+// statement, with their aliases.
+func tableRefs(tokens []sqlToken) []tableRef {
+	var refs []tableRef
+
+	for i, tok := range tokens {
+		if tok.kind != tokWord {
+			continue
+		}
+
+		kw := strings.ToLower(tok.text)
+		if kw != "from" && kw != "join" && kw != "update" && kw != "into" {
+			continue
+		}
+
+		j := i + 1
+		for {
+			if j < len(tokens) && tokens[j].kind == tokWord && slices.Contains([]string{"only", "lateral"}, strings.ToLower(tokens[j].text)) {
+				j++
+			}
+
+			ref, next, ok := parseTableRef(tokens, j)
+			if !ok {
+				break
+			}
+			refs = append(refs, ref)
+
+			if kw != "from" || next >= len(tokens) || !isPunct(tokens[next], ",") {
+				break
+			}
+			j = next + 1
+		}
+	}
+
+	return refs
+}
+
+// parseTableRef reads "[schema.]table [[AS] alias]" at tokens[i].
+func parseTableRef(tokens []sqlToken, i int) (tableRef, int, bool) {
+	if i >= len(tokens) || !isName(tokens[i]) {
+		return tableRef{}, i, false
+	}
+
+	ref := tableRef{table: identName(tokens[i])}
+	i++
+
+	if i+1 < len(tokens) && isPunct(tokens[i], ".") && isIdent(tokens[i+1]) {
+		ref.schema = ref.table
+		ref.table = identName(tokens[i+1])
+		i += 2
+	}
+
+	// The column list of INSERT INTO t (...), or the arguments of a function
+	// such as generate_series(...), which then matches no table.
+	if i < len(tokens) && isPunct(tokens[i], "(") {
+		return ref, i, true
+	}
+
+	if i < len(tokens) && tokens[i].kind == tokWord && strings.EqualFold(tokens[i].text, "as") {
+		i++
+	}
+	if i < len(tokens) && isName(tokens[i]) {
+		ref.alias = identName(tokens[i])
+		i++
+	}
+
+	return ref, i, true
+}
+
+// statementAt returns the tokens of the statement around pos, comments
+// left out.
+func statementAt(tokens []sqlToken, pos int) []sqlToken {
+	var stmt []sqlToken
+
+	for _, tok := range tokens {
+		if isPunct(tok, ";") {
+			if tok.start >= pos {
+				break
+			}
+			stmt = stmt[:0]
+			continue
+		}
+		if tok.kind != tokComment {
+			stmt = append(stmt, tok)
+		}
+	}
+
+	return stmt
+}
+
+// tokenize splits sql into words, quoted identifiers, literals, punctuation
+// and comments, keeping their byte offsets.
+func tokenize(sql string) []sqlToken {
+	var tokens []sqlToken
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokComment, start: start, end: i})
+
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			depth, closed := 0, false
+			for i < len(sql) {
+				if strings.HasPrefix(sql[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(sql[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						closed = true
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: tokComment, start: start, end: i, closed: closed})
+
+		case c == '\'':
+			end, closed := quotedEnd(sql, i, '\'', false)
+			i = end
+			tokens = append(tokens, sqlToken{kind: tokString, start: start, end: i, closed: closed})
+
+		case c == '"':
+			end, closed := quotedEnd(sql, i, '"', false)
+			i = end
+			text := sql[start+1 : end]
+			if closed {
+				text = sql[start+1 : end-1]
+			}
+			tokens = append(tokens, sqlToken{kind: tokQuoted, text: strings.ReplaceAll(text, `""`, `"`), start: start, end: i, closed: closed})
+
+		case c == '$':
+			tag, ok := dollarTag(sql[i:])
+			if !ok {
+				i++
+				tokens = append(tokens, sqlToken{kind: tokPunct, text: "$", start: start, end: i, closed: true})
+				break
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			closed := end >= 0
+			if closed {
+				i += len(tag) + end + len(tag)
+			} else {
+				i = len(sql)
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, start: start, end: i, closed: closed})
+
+		case c >= '0' && c <= '9':
+			for i < len(sql) && (isIdentChar(sql[i]) || sql[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokNumber, text: sql[start:i], start: start, end: i, closed: true})
+
+		case isIdentChar(c):
+			for i < len(sql) && (isIdentChar(sql[i]) || sql[i] == '$') {
+				i++
+			}
+
+			// E'...' strings allow backslash escapes.
+			if i < len(sql) && sql[i] == '\'' && strings.EqualFold(sql[start:i], "e") {
+				end, closed := quotedEnd(sql, i, '\'', true)
+				i = end
+				tokens = append(tokens, sqlToken{kind: tokString, start: start, end: i, closed: closed})
+				break
+			}
+
+			tokens = append(tokens, sqlToken{kind: tokWord, text: sql[start:i], start: start, end: i, closed: true})
+
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: sql[start:i], start: start, end: i, closed: true})
+		}
+	}
+
+	return tokens
+}
+
+// quotedEnd returns the offset right after the literal or identifier opened
+// by the quote at sql[i], and whether it was closed. Doubled quotes are part
+// of the text.
+func quotedEnd(sql string, i int, quote byte, escapes bool) (int, bool) {
+	for i++; i < len(sql); i++ {
+		if escapes && sql[i] == '\\' {
+			i++
+			continue
+		}
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, true
+		}
+	}
+
+	return len(sql), false
+}
+
+func isPunct(tok sqlToken, text string) bool {
+	return tok.kind == tokPunct && tok.text == text
+}
+
+func isIdent(tok sqlToken) bool {
+	return tok.kind == tokWord || tok.kind == tokQuoted
+}
+
+// isName reports whether tok can name a table or alias: a quoted identifier
+// or a word that isn't a keyword.
+func isName(tok sqlToken) bool {
+	return tok.kind == tokQuoted || (tok.kind == tokWord && !slices.Contains(completionKeywords, strings.ToLower(tok.text)))
+}
+
+// identName returns the name an identifier refers to: unquoted names are
+// folded to lower case.
+func identName(tok sqlToken) string {
+	if tok.kind == tokQuoted {
+		return tok.text
+	}
+	return strings.ToLower(tok.text)
+}
+
+// quoteIdent quotes name unless it can be written as is.
+func quoteIdent(name string) string {
+	simple := name != "" && !slices.Contains(completionKeywords, name) && !(name[0] >= '0' && name[0] <= '9')
+	for i := 0; simple && i < len(name); i++ {
+		c := name[i]
+		simple = c == '_' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '$'
+	}
+
+	if simple {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// byteOffset converts a character offset into a byte offset in s, clamped
+// to its length.
+func byteOffset(s string, chars int) int {
+	if chars < 0 {
+		return 0
+	}
+
+	for i := range s {
+		if chars == 0 {
+			return i
+		}
+		chars--
+	}
+
+	return len(s)
+}
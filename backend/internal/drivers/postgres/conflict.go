@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// conflictClause builds the ON CONFLICT clause of an insert of the columns
+// names, empty when conflicts are not handled.
+func conflictClause(columns []models.ColumnModel, names []string, opts models.InsertOptions) (string, error) {
+	if opts.OnConflict == "" {
+		return "", nil
+	}
+
+	var target string
+	switch {
+	case opts.ConflictConstraint != "" && len(opts.ConflictColumns) > 0:
+		return "", fmt.Errorf("%w: give either a conflict constraint or conflict columns", models.ErrInvalidRow)
+
+	case opts.ConflictConstraint != "":
+		target = " ON CONSTRAINT " + pgx.Identifier{opts.ConflictConstraint}.Sanitize()
+
+	case len(opts.ConflictColumns) > 0:
+		cols := make([]string, len(opts.ConflictColumns))
+		for i, name := range opts.ConflictColumns {
+			if !slices.ContainsFunc(columns, func(c models.ColumnModel) bool { return c.Name == name }) {
+				return "", fmt.Errorf("%w: unknown conflict column %q", models.ErrInvalidRow, name)
+			}
+			cols[i] = pgx.Identifier{name}.Sanitize()
+		}
+		target = " (" + strings.Join(cols, ", ") + ")"
+
+	case opts.OnConflict == models.OnConflictDoUpdate:
+		return "", fmt.Errorf("%w: do_update needs a conflict constraint or conflict columns", models.ErrInvalidRow)
+	}
+
+	switch opts.OnConflict {
+	case models.OnConflictDoNothing:
+		return " ON CONFLICT" + target + " DO NOTHING", nil
+
+	case models.OnConflictDoUpdate:
+		// The conflict columns already hold the inserted values, unless
+		// they are all there is to set.
+		set := slices.DeleteFunc(slices.Clone(names), func(name string) bool { return slices.Contains(opts.ConflictColumns, name) })
+		if len(set) == 0 {
+			set = names
+		}
+		if len(set) == 0 {
+			return "", fmt.Errorf("%w: do_update needs values to set", models.ErrInvalidRow)
+		}
+
+		assignments := make([]string, len(set))
+		for i, name := range set {
+			col := pgx.Identifier{name}.Sanitize()
+			assignments[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+		}
+		return " ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(assignments, ", "), nil
+
+	default:
+		return "", fmt.Errorf("%w: on_conflict must be do_nothing or do_update", models.ErrInvalidRow)
+	}
+}
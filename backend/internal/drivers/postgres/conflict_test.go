@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+func TestConflictClause(t *testing.T) {
+	columns := []models.ColumnModel{{Name: "id"}, {Name: "org"}, {Name: "name"}, {Name: "age"}}
+
+	tests := []struct {
+		name  string
+		names []string
+		opts  models.InsertOptions
+		want  string
+	}{
+		{
+			name:  "no conflict handling",
+			names: []string{"id"},
+			want:  "",
+		},
+		{
+			name:  "do nothing on any conflict",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoNothing},
+			want:  " ON CONFLICT DO NOTHING",
+		},
+		{
+			name:  "do nothing on constraint",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoNothing, ConflictConstraint: "users_pkey"},
+			want:  ` ON CONFLICT ON CONSTRAINT "users_pkey" DO NOTHING`,
+		},
+		{
+			name:  "do nothing on columns",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoNothing, ConflictColumns: []string{"id"}},
+			want:  ` ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:  "do update skips the conflict columns",
+			names: []string{"age", "id", "name"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictColumns: []string{"id"}},
+			want:  ` ON CONFLICT ("id") DO UPDATE SET "age" = EXCLUDED."age", "name" = EXCLUDED."name"`,
+		},
+		{
+			name:  "do update on composite columns",
+			names: []string{"id", "name", "org"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictColumns: []string{"org", "id"}},
+			want:  ` ON CONFLICT ("org", "id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			name:  "do update on constraint sets every column",
+			names: []string{"id", "name"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictConstraint: "users_pkey"},
+			want:  ` ON CONFLICT ON CONSTRAINT "users_pkey" DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name"`,
+		},
+		{
+			name:  "do update with only conflict columns",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictColumns: []string{"id"}},
+			want:  ` ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id"`,
+		},
+		{
+			name:  "quoted names",
+			names: []string{"na\"me"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictConstraint: "a\"b"},
+			want:  ` ON CONFLICT ON CONSTRAINT "a""b" DO UPDATE SET "na""me" = EXCLUDED."na""me"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := conflictClause(columns, tt.names, tt.opts)
+			if err != nil {
+				t.Fatalf("conflictClause() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("conflictClause() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictClauseErrors(t *testing.T) {
+	columns := []models.ColumnModel{{Name: "id"}, {Name: "name"}}
+
+	tests := []struct {
+		name  string
+		names []string
+		opts  models.InsertOptions
+	}{
+		{
+			name:  "constraint and columns",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoNothing, ConflictConstraint: "c", ConflictColumns: []string{"id"}},
+		},
+		{
+			name:  "unknown conflict column",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoNothing, ConflictColumns: []string{"nope"}},
+		},
+		{
+			name:  "do update without target",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate},
+		},
+		{
+			name:  "do update without values",
+			names: nil,
+			opts:  models.InsertOptions{OnConflict: models.OnConflictDoUpdate, ConflictColumns: []string{"id"}},
+		},
+		{
+			name:  "unknown action",
+			names: []string{"id"},
+			opts:  models.InsertOptions{OnConflict: "replace"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := conflictClause(columns, tt.names, tt.opts)
+			if !errors.Is(err, models.ErrInvalidRow) {
+				t.Errorf("conflictClause() error = %v, want %v", err, models.ErrInvalidRow)
+			}
+		})
+	}
+}
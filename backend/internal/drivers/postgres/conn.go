@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// acquire takes a connection from the pool and reports its backend pid to
+// the activity tracker, so a running query can be cancelled server side.
+// The connection must be given back with the returned function, which also
+// withdraws the pid.
+func acquire(ctx context.Context, db *pgxpool.Pool) (*pgxpool.Conn, func(), error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clear := activity.SetPID(ctx, conn.Conn().PgConn().PID())
+
+	return conn, func() {
+		clear()
+		conn.Release()
+	}, nil
+}
+
+// CancelBackend asks the server to cancel whatever the backend with the
+// given pid is running.
+func CancelBackend(ctx context.Context, db *pgxpool.Pool, pid uint32) (*models.ApiResponse, error) {
+	var cancelled bool
+	if err := db.QueryRow(ctx, `SELECT pg_cancel_backend($1)`, int32(pid)).Scan(&cancelled); err != nil {
+		return nil, err
+	}
+
+	if !cancelled {
+		return nil, errors.New("could not cancel the backend running the query")
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "cancelled",
+	}, nil
+}
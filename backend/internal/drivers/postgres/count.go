@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// countRows counts the rows of the table matching where.
+func countRows(ctx context.Context, conn *pgx.Conn, schema string, table string, where string, params []models.QueryParam) (int64, error) {
+	query := fmt.Sprintf(`SELECT count(*) FROM %s%s`, pgx.Identifier{schema, table}.Sanitize(), where)
+
+	qr, err := runStatement(ctx, conn, query, params, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(qr.Rows) == 0 || len(qr.Rows[0]) == 0 {
+		return 0, errors.New("count returned no rows")
+	}
+
+	total, ok := qr.Rows[0][0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count %v", qr.Rows[0][0])
+	}
+
+	return total, nil
+}
+
+// estimateRows guesses how many rows of the table match where without
+// reading them. The whole table is estimated from pg_class.reltuples;
+// filtered rows, and tables never vacuumed or analyzed, from the row
+// estimate of the planner.
+func estimateRows(ctx context.Context, conn *pgx.Conn, schema string, table string, where string, params []models.QueryParam) (int64, error) {
+	if where == "" {
+		var reltuples float64
+		err := conn.QueryRow(
+			ctx,
+			`SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)`,
+			pgx.Identifier{schema, table}.Sanitize(),
+		).Scan(&reltuples)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return 0, err
+		}
+		if err == nil && reltuples >= 0 {
+			return int64(reltuples), nil
+		}
+	}
+
+	query := fmt.Sprintf(`EXPLAIN (FORMAT JSON) SELECT * FROM %s%s`, pgx.Identifier{schema, table}.Sanitize(), where)
+
+	qr, err := runStatement(ctx, conn, query, params, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(qr.Rows) == 0 || len(qr.Rows[0]) == 0 {
+		return 0, errors.New("explain returned no plan")
+	}
+
+	doc, err := json.Marshal(qr.Rows[0][0])
+	if err != nil {
+		return 0, err
+	}
+
+	var outputs []explainOutput
+	if err := json.Unmarshal(doc, &outputs); err != nil {
+		return 0, err
+	}
+	if len(outputs) == 0 {
+		return 0, errors.New("explain returned no plan")
+	}
+
+	return int64(outputs[0].Plan.PlanRows), nil
+}
@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// explainOutput mirrors the document produced by EXPLAIN (FORMAT JSON).
+type explainOutput struct {
+	Plan          explainNode `json:"Plan"`
+	PlanningTime  *float64    `json:"Planning Time"`
+	ExecutionTime *float64    `json:"Execution Time"`
+}
+
+type explainNode struct {
+	NodeType          string        `json:"Node Type"`
+	RelationName      string        `json:"Relation Name"`
+	Schema            string        `json:"Schema"`
+	Alias             string        `json:"Alias"`
+	IndexName         string        `json:"Index Name"`
+	JoinType          string        `json:"Join Type"`
+	IndexCond         string        `json:"Index Cond"`
+	HashCond          string        `json:"Hash Cond"`
+	MergeCond         string        `json:"Merge Cond"`
+	RecheckCond       string        `json:"Recheck Cond"`
+	Filter            string        `json:"Filter"`
+	JoinFilter        string        `json:"Join Filter"`
+	StartupCost       float64       `json:"Startup Cost"`
+	TotalCost         float64       `json:"Total Cost"`
+	PlanRows          float64       `json:"Plan Rows"`
+	ActualTotalTime   *float64      `json:"Actual Total Time"`
+	ActualRows        *float64      `json:"Actual Rows"`
+	ActualLoops       *float64      `json:"Actual Loops"`
+	SharedHitBlocks   *int64        `json:"Shared Hit Blocks"`
+	SharedReadBlocks  int64         `json:"Shared Read Blocks"`
+	SharedDirtied     int64         `json:"Shared Dirtied Blocks"`
+	SharedWritten     int64         `json:"Shared Written Blocks"`
+	TempReadBlocks    int64         `json:"Temp Read Blocks"`
+	TempWrittenBlocks int64         `json:"Temp Written Blocks"`
+	Plans             []explainNode `json:"Plans"`
+}
+
+// ExplainQuery runs EXPLAIN (FORMAT JSON) for a single statement and returns
+// the plan as a normalized tree. The statement always runs inside a
+// transaction that is rolled back, so EXPLAIN ANALYZE on DML has no effect.
+func ExplainQuery(ctx context.Context, db *pgxpool.Pool, req models.ExplainRequest) (*models.ApiResponse, error) {
+	statements := SplitStatements(req.Query)
+	if len(statements) != 1 {
+		return nil, errors.New("explain expects exactly one statement")
+	}
+
+	options := []string{"FORMAT JSON"}
+	if req.Analyze {
+		options = append(options, "ANALYZE")
+	}
+	if req.Buffers {
+		options = append(options, "BUFFERS")
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, req.TimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	prefix := fmt.Sprintf("EXPLAIN (%s) ", strings.Join(options, ", "))
+	qr, err := runStatement(
+		ctx,
+		tx.Conn(),
+		prefix+statements[0],
+		req.Params,
+		hints,
+		0,
+	)
+	if err != nil {
+		pgerror.ShiftPosition(err, statementOffsets(req.Query, statements)[0]-len(prefix))
+		return nil, readOnlyError(err)
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(qr.Rows) == 0 || len(qr.Rows[0]) == 0 {
+		return nil, errors.New("explain returned no plan")
+	}
+
+	raw := qr.Rows[0][0]
+	doc, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []explainOutput
+	if err := json.Unmarshal(doc, &outputs); err != nil {
+		return nil, err
+	}
+	if len(outputs) == 0 {
+		return nil, errors.New("explain returned no plan")
+	}
+
+	plan := planNode(outputs[0].Plan)
+	if req.Analyze {
+		markSlowest(plan)
+		markMostMisestimated(plan)
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data: models.ExplainResult{
+			Plan:            plan,
+			PlanningTimeMs:  outputs[0].PlanningTime,
+			ExecutionTimeMs: outputs[0].ExecutionTime,
+			Raw:             raw,
+		},
+	}, nil
+}
+
+func planNode(n explainNode) *models.PlanNode {
+	node := &models.PlanNode{
+		NodeType:      n.NodeType,
+		Relation:      n.RelationName,
+		Schema:        n.Schema,
+		Alias:         n.Alias,
+		Index:         n.IndexName,
+		JoinType:      n.JoinType,
+		Condition:     firstNonEmpty(n.IndexCond, n.HashCond, n.MergeCond, n.RecheckCond, n.JoinFilter),
+		Filter:        n.Filter,
+		StartupCost:   n.StartupCost,
+		TotalCost:     n.TotalCost,
+		EstimatedRows: n.PlanRows,
+		ActualRows:    n.ActualRows,
+		Loops:         n.ActualLoops,
+		Children:      make([]*models.PlanNode, len(n.Plans)),
+	}
+
+	if n.SharedHitBlocks != nil {
+		node.Buffers = &models.PlanBuffers{
+			SharedHit:     *n.SharedHitBlocks,
+			SharedRead:    n.SharedReadBlocks,
+			SharedDirtied: n.SharedDirtied,
+			SharedWritten: n.SharedWritten,
+			TempRead:      n.TempReadBlocks,
+			TempWritten:   n.TempWrittenBlocks,
+		}
+	}
+
+	for i, child := range n.Plans {
+		node.Children[i] = planNode(child)
+	}
+
+	// Actual times and rows are averages per loop; the total time of a
+	// node includes the time spent in its children.
+	if n.ActualTotalTime != nil && n.ActualLoops != nil {
+		total := *n.ActualTotalTime * *n.ActualLoops
+		exclusive := total
+		for _, child := range node.Children {
+			if child.TotalTimeMs != nil {
+				exclusive -= *child.TotalTimeMs
+			}
+		}
+		node.TotalTimeMs = &total
+		node.ExclusiveTimeMs = ptr(max(exclusive, 0))
+	}
+
+	if n.ActualRows != nil && (n.ActualLoops == nil || *n.ActualLoops > 0) {
+		estimated, actual := max(n.PlanRows, 1), max(*n.ActualRows, 1)
+		node.MisestimateFactor = ptr(max(estimated/actual, actual/estimated))
+	}
+
+	return node
+}
+
+// markSlowest flags the node with the highest exclusive time.
+func markSlowest(root *models.PlanNode) {
+	if slowest := findNode(root, func(n *models.PlanNode) *float64 { return n.ExclusiveTimeMs }); slowest != nil {
+		slowest.Slowest = true
+	}
+}
+
+// markMostMisestimated flags the node whose row estimate is furthest off.
+func markMostMisestimated(root *models.PlanNode) {
+	node := findNode(root, func(n *models.PlanNode) *float64 { return n.MisestimateFactor })
+	if node != nil && *node.MisestimateFactor > 1 {
+		node.MostMisestimated = true
+	}
+}
+
+// findNode returns the node with the highest metric in the tree.
+func findNode(node *models.PlanNode, metric func(*models.PlanNode) *float64) *models.PlanNode {
+	var best *models.PlanNode
+	if metric(node) != nil {
+		best = node
+	}
+
+	for _, child := range node.Children {
+		candidate := findNode(child, metric)
+		if candidate != nil && (best == nil || *metric(candidate) > *metric(best)) {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+// parsePlan reads the plan of an EXPLAIN (FORMAT JSON) document.
+func parsePlan(t *testing.T, doc string) *models.PlanNode {
+	t.Helper()
+
+	var outputs []explainOutput
+	if err := json.Unmarshal([]byte(doc), &outputs); err != nil {
+		t.Fatal(err)
+	}
+	return planNode(outputs[0].Plan)
+}
+
+// flatten lists the nodes of the tree depth first.
+func flatten(node *models.PlanNode) []*models.PlanNode {
+	nodes := []*models.PlanNode{node}
+	for _, child := range node.Children {
+		nodes = append(nodes, flatten(child)...)
+	}
+	return nodes
+}
+
+// asJSON formats v for failure messages.
+func asJSON(v any) string {
+	doc, _ := json.Marshal(v)
+	return string(doc)
+}
+
+const hashJoinPlan = `[{"Plan": {
+	"Node Type": "Hash Join", "Join Type": "Inner", "Hash Cond": "(a.id = b.a_id)",
+	"Plan Rows": 100, "Actual Rows": 100, "Actual Loops": 1, "Actual Total Time": 10,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "a", "Filter": "(x > 1)",
+		 "Plan Rows": 1000, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 4},
+		{"Node Type": "Hash", "Plan Rows": 50, "Actual Rows": 50, "Actual Loops": 1, "Actual Total Time": 3,
+		 "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "b",
+			 "Plan Rows": 50, "Actual Rows": 50, "Actual Loops": 1, "Actual Total Time": 2}
+		 ]}
+	]},
+	"Planning Time": 0.1, "Execution Time": 10.2}]`
+
+func TestPlanNodeTimes(t *testing.T) {
+	type times struct {
+		Total, Exclusive, Factor *float64
+	}
+	f := ptr[float64]
+
+	tests := []struct {
+		name string
+		doc  string
+		want []times
+	}{
+		{
+			name: "exclusive time leaves out the children",
+			doc:  hashJoinPlan,
+			want: []times{
+				{Total: f(10), Exclusive: f(3), Factor: f(1)},
+				{Total: f(4), Exclusive: f(4), Factor: f(100)},
+				{Total: f(3), Exclusive: f(1), Factor: f(1)},
+				{Total: f(2), Exclusive: f(2), Factor: f(1)},
+			},
+		},
+		{
+			name: "times are multiplied by the loops",
+			doc: `[{"Plan": {
+				"Node Type": "Nested Loop", "Plan Rows": 8, "Actual Rows": 8, "Actual Loops": 1, "Actual Total Time": 5,
+				"Plans": [
+					{"Node Type": "Index Scan", "Index Name": "b_pkey", "Index Cond": "(id = a.b_id)", "Filter": "(y)",
+					 "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 8, "Actual Total Time": 0.5}
+				]}}]`,
+			want: []times{
+				{Total: f(5), Exclusive: f(1), Factor: f(1)},
+				{Total: f(4), Exclusive: f(4), Factor: f(1)},
+			},
+		},
+		{
+			name: "exclusive time is never negative",
+			doc: `[{"Plan": {
+				"Node Type": "Gather", "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 1,
+				"Plans": [
+					{"Node Type": "Parallel Seq Scan", "Plan Rows": 5, "Actual Rows": 5, "Actual Loops": 3, "Actual Total Time": 1}
+				]}}]`,
+			want: []times{
+				{Total: f(1), Exclusive: f(0), Factor: f(1)},
+				{Total: f(3), Exclusive: f(3), Factor: f(1)},
+			},
+		},
+		{
+			name: "node never executed",
+			doc: `[{"Plan": {
+				"Node Type": "Result", "Plan Rows": 1, "Actual Rows": 0, "Actual Loops": 0, "Actual Total Time": 0}}]`,
+			want: []times{
+				{Total: f(0), Exclusive: f(0)},
+			},
+		},
+		{
+			name: "underestimate counts like an overestimate",
+			doc: `[{"Plan": {
+				"Node Type": "Seq Scan", "Plan Rows": 2, "Actual Rows": 50, "Actual Loops": 1, "Actual Total Time": 1}}]`,
+			want: []times{
+				{Total: f(1), Exclusive: f(1), Factor: f(25)},
+			},
+		},
+		{
+			name: "zero rows are counted as one",
+			doc: `[{"Plan": {
+				"Node Type": "Seq Scan", "Plan Rows": 10, "Actual Rows": 0, "Actual Loops": 1, "Actual Total Time": 1}}]`,
+			want: []times{
+				{Total: f(1), Exclusive: f(1), Factor: f(10)},
+			},
+		},
+		{
+			name: "without analyze",
+			doc:  `[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 10}}]`,
+			want: []times{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []times
+			for _, n := range flatten(parsePlan(t, tt.doc)) {
+				got = append(got, times{Total: n.TotalTimeMs, Exclusive: n.ExclusiveTimeMs, Factor: n.MisestimateFactor})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("times = %s, want %s", asJSON(got), asJSON(tt.want))
+			}
+		})
+	}
+}
+
+func TestPlanNodeFields(t *testing.T) {
+	doc := `[{"Plan": {
+		"Node Type": "Index Scan", "Relation Name": "users", "Schema": "public", "Alias": "u",
+		"Index Name": "users_pkey", "Index Cond": "(id = 1)", "Recheck Cond": "(id > 0)", "Filter": "(active)",
+		"Startup Cost": 0.29, "Total Cost": 8.3, "Plan Rows": 1,
+		"Shared Hit Blocks": 3, "Shared Read Blocks": 1, "Temp Written Blocks": 2}}]`
+
+	got := parsePlan(t, doc)
+	want := &models.PlanNode{
+		NodeType:      "Index Scan",
+		Relation:      "users",
+		Schema:        "public",
+		Alias:         "u",
+		Index:         "users_pkey",
+		Condition:     "(id = 1)",
+		Filter:        "(active)",
+		StartupCost:   0.29,
+		TotalCost:     8.3,
+		EstimatedRows: 1,
+		Buffers:       &models.PlanBuffers{SharedHit: 3, SharedRead: 1, TempWritten: 2},
+		Children:      []*models.PlanNode{},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planNode() = %s, want %s", asJSON(got), asJSON(want))
+	}
+}
+
+func TestMarkPlanNodes(t *testing.T) {
+	tests := []struct {
+		name             string
+		doc              string
+		slowest          []string
+		mostMisestimated []string
+	}{
+		{
+			name:             "hash join",
+			doc:              hashJoinPlan,
+			slowest:          []string{"Seq Scan a"},
+			mostMisestimated: []string{"Seq Scan a"},
+		},
+		{
+			name: "accurate estimates",
+			doc: `[{"Plan": {
+				"Node Type": "Limit", "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 1, "Actual Total Time": 2,
+				"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 1, "Actual Total Time": 0.5}]}}]`,
+			slowest: []string{"Limit "},
+		},
+		{
+			name: "without analyze",
+			doc:  `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Plan Rows": 10}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := parsePlan(t, tt.doc)
+			markSlowest(root)
+			markMostMisestimated(root)
+
+			var slowest, mostMisestimated []string
+			for _, n := range flatten(root) {
+				label := n.NodeType + " " + n.Relation
+				if n.Slowest {
+					slowest = append(slowest, label)
+				}
+				if n.MostMisestimated {
+					mostMisestimated = append(mostMisestimated, label)
+				}
+			}
+
+			if !reflect.DeepEqual(slowest, tt.slowest) {
+				t.Errorf("slowest = %q, want %q", slowest, tt.slowest)
+			}
+			if !reflect.DeepEqual(mostMisestimated, tt.mostMisestimated) {
+				t.Errorf("most misestimated = %q, want %q", mostMisestimated, tt.mostMisestimated)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ExportRows writes the rows of the table to w as CSV, with a header line
+// holding the column names, in a read-only transaction. Like streamed rows,
+// the export is bounded by limits.TimeoutMs and stops after limits.MaxRows
+// rows, when set.
+func ExportRows(ctx context.Context, db *pgxpool.Pool, schema string, table string, limits models.QueryLimits, w io.Writer) error {
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), true, limits.TimeoutMs)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT * FROM `+pgx.Identifier{schema, table}.Sanitize())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	headers := make([]string, len(fieldDescriptions))
+	for i, fd := range fieldDescriptions {
+		headers[i] = string(fd.Name)
+	}
+
+	if err := csvWriter.Write(headers); err != nil {
+		return err
+	}
+
+	values := make([]any, len(headers))
+	valuesPtrs := make([]any, len(headers))
+
+	for i := range values {
+		valuesPtrs[i] = &values[i]
+	}
+
+	read := 0
+	for rows.Next() {
+		if limits.MaxRows > 0 && read >= limits.MaxRows {
+			break
+		}
+
+		if err := rows.Scan(valuesPtrs...); err != nil {
+			return err
+		}
+
+		record := make([]string, len(values))
+		for i, v := range values {
+			if v == nil {
+				record[i] = ""
+			} else {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+		read++
+	}
+
+	return rows.Err()
+}
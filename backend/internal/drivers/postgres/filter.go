@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+var comparisonOperators = map[string]string{
+	models.FilterEq:  "=",
+	models.FilterNeq: "<>",
+	models.FilterLt:  "<",
+	models.FilterLte: "<=",
+	models.FilterGt:  ">",
+	models.FilterGte: ">=",
+}
+
+var patternOperators = map[string]string{
+	models.FilterLike:     "LIKE",
+	models.FilterNotLike:  "NOT LIKE",
+	models.FilterILike:    "ILIKE",
+	models.FilterNotILike: "NOT ILIKE",
+}
+
+// filterBuilder compiles a row filter into a WHERE condition. Values are
+// never inlined: each one becomes a parameter whose type the server infers
+// from the column it is compared to.
+type filterBuilder struct {
+	columns []models.ColumnModel
+	params  []models.QueryParam
+}
+
+func newFilterBuilder(columns []models.ColumnModel) *filterBuilder {
+	return &filterBuilder{columns: columns}
+}
+
+func (b *filterBuilder) compile(f models.RowFilter) (string, error) {
+	switch {
+	case f.And != nil && f.Or == nil && f.Column == "":
+		return b.group(f.And, " AND ")
+	case f.Or != nil && f.And == nil && f.Column == "":
+		return b.group(f.Or, " OR ")
+	case f.Column != "" && f.And == nil && f.Or == nil:
+		return b.condition(f)
+	default:
+		return "", fmt.Errorf("%w: each filter needs exactly one of and, or, column", models.ErrInvalidFilter)
+	}
+}
+
+func (b *filterBuilder) group(filters []models.RowFilter, sep string) (string, error) {
+	if len(filters) == 0 {
+		return "", fmt.Errorf("%w: and/or groups cannot be empty", models.ErrInvalidFilter)
+	}
+
+	conds := make([]string, len(filters))
+	for i, f := range filters {
+		cond, err := b.compile(f)
+		if err != nil {
+			return "", err
+		}
+		conds[i] = cond
+	}
+
+	return "(" + strings.Join(conds, sep) + ")", nil
+}
+
+func (b *filterBuilder) condition(f models.RowFilter) (string, error) {
+	if !b.known(f.Column) {
+		return "", fmt.Errorf("%w: unknown column %q", models.ErrInvalidFilter, f.Column)
+	}
+	col := pgx.Identifier{f.Column}.Sanitize()
+
+	if op, ok := comparisonOperators[f.Op]; ok {
+		if f.Value == nil {
+			return "", fmt.Errorf("%w: %s on %q needs a value", models.ErrInvalidFilter, f.Op, f.Column)
+		}
+		return fmt.Sprintf("%s %s %s", col, op, b.param(f.Value)), nil
+	}
+
+	// Patterns match the text form of any column, not only text ones.
+	if op, ok := patternOperators[f.Op]; ok {
+		if _, isString := f.Value.(string); !isString {
+			return "", fmt.Errorf("%w: %s on %q needs a string pattern", models.ErrInvalidFilter, f.Op, f.Column)
+		}
+		return fmt.Sprintf("%s::text %s %s", col, op, b.param(f.Value)), nil
+	}
+
+	switch f.Op {
+	case models.FilterIn, models.FilterNotIn:
+		list, ok := f.Value.([]any)
+		if !ok {
+			return "", fmt.Errorf("%w: %s on %q needs a list of values", models.ErrInvalidFilter, f.Op, f.Column)
+		}
+		if f.Op == models.FilterIn {
+			return fmt.Sprintf("%s = ANY(%s)", col, b.param(list)), nil
+		}
+		return fmt.Sprintf("%s <> ALL(%s)", col, b.param(list)), nil
+
+	case models.FilterBetween:
+		bounds, ok := f.Value.([]any)
+		if !ok || len(bounds) != 2 || bounds[0] == nil || bounds[1] == nil {
+			return "", fmt.Errorf("%w: between on %q needs a [low, high] pair", models.ErrInvalidFilter, f.Column)
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", col, b.param(bounds[0]), b.param(bounds[1])), nil
+
+	case models.FilterIsNull:
+		return col + " IS NULL", nil
+
+	case models.FilterNotNull:
+		return col + " IS NOT NULL", nil
+
+	default:
+		return "", fmt.Errorf("%w: unknown operator %q", models.ErrInvalidFilter, f.Op)
+	}
+}
+
+func (b *filterBuilder) known(column string) bool {
+	for _, c := range b.columns {
+		if c.Name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// param adds value to the parameters and returns its placeholder.
+func (b *filterBuilder) param(value any) string {
+	b.params = append(b.params, models.QueryParam{Value: value})
+	return fmt.Sprintf("$%d", len(b.params))
+}
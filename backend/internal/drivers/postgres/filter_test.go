@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+func TestFilterBuilderCompile(t *testing.T) {
+	columns := []models.ColumnModel{{Name: "id"}, {Name: "name"}, {Name: "created at"}}
+
+	tests := []struct {
+		name   string
+		filter models.RowFilter
+		want   string
+		params []any
+	}{
+		{
+			name:   "eq",
+			filter: models.RowFilter{Column: "id", Op: models.FilterEq, Value: 1},
+			want:   `"id" = $1`,
+			params: []any{1},
+		},
+		{
+			name:   "gte",
+			filter: models.RowFilter{Column: "id", Op: models.FilterGte, Value: 2},
+			want:   `"id" >= $1`,
+			params: []any{2},
+		},
+		{
+			name:   "neq",
+			filter: models.RowFilter{Column: "id", Op: models.FilterNeq, Value: 3},
+			want:   `"id" <> $1`,
+			params: []any{3},
+		},
+		{
+			name:   "quoted column",
+			filter: models.RowFilter{Column: "created at", Op: models.FilterLt, Value: "2024-01-01"},
+			want:   `"created at" < $1`,
+			params: []any{"2024-01-01"},
+		},
+		{
+			name:   "ilike casts to text",
+			filter: models.RowFilter{Column: "id", Op: models.FilterILike, Value: "1%"},
+			want:   `"id"::text ILIKE $1`,
+			params: []any{"1%"},
+		},
+		{
+			name:   "not like",
+			filter: models.RowFilter{Column: "name", Op: models.FilterNotLike, Value: "a%"},
+			want:   `"name"::text NOT LIKE $1`,
+			params: []any{"a%"},
+		},
+		{
+			name:   "in",
+			filter: models.RowFilter{Column: "id", Op: models.FilterIn, Value: []any{1, 2}},
+			want:   `"id" = ANY($1)`,
+			params: []any{[]any{1, 2}},
+		},
+		{
+			name:   "not in",
+			filter: models.RowFilter{Column: "id", Op: models.FilterNotIn, Value: []any{1}},
+			want:   `"id" <> ALL($1)`,
+			params: []any{[]any{1}},
+		},
+		{
+			name:   "between",
+			filter: models.RowFilter{Column: "id", Op: models.FilterBetween, Value: []any{1, 9}},
+			want:   `"id" BETWEEN $1 AND $2`,
+			params: []any{1, 9},
+		},
+		{
+			name:   "is null",
+			filter: models.RowFilter{Column: "name", Op: models.FilterIsNull},
+			want:   `"name" IS NULL`,
+		},
+		{
+			name:   "not null",
+			filter: models.RowFilter{Column: "name", Op: models.FilterNotNull},
+			want:   `"name" IS NOT NULL`,
+		},
+		{
+			name: "nested groups",
+			filter: models.RowFilter{And: []models.RowFilter{
+				{Column: "id", Op: models.FilterGt, Value: 1},
+				{Or: []models.RowFilter{
+					{Column: "name", Op: models.FilterEq, Value: "a"},
+					{Column: "name", Op: models.FilterIsNull},
+				}},
+			}},
+			want:   `("id" > $1 AND ("name" = $2 OR "name" IS NULL))`,
+			params: []any{1, "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFilterBuilder(columns)
+			got, err := b.compile(tt.filter)
+			if err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("compile() = %s, want %s", got, tt.want)
+			}
+
+			var params []any
+			for _, p := range b.params {
+				params = append(params, p.Value)
+			}
+			if !reflect.DeepEqual(params, tt.params) {
+				t.Errorf("params = %v, want %v", params, tt.params)
+			}
+		})
+	}
+}
+
+func TestFilterBuilderCompileErrors(t *testing.T) {
+	columns := []models.ColumnModel{{Name: "id"}}
+
+	tests := []struct {
+		name   string
+		filter models.RowFilter
+	}{
+		{name: "empty filter", filter: models.RowFilter{}},
+		{name: "column and group", filter: models.RowFilter{Column: "id", Op: models.FilterIsNull, And: []models.RowFilter{}}},
+		{name: "and and or", filter: models.RowFilter{And: []models.RowFilter{}, Or: []models.RowFilter{}}},
+		{name: "empty group", filter: models.RowFilter{And: []models.RowFilter{}}},
+		{name: "unknown column", filter: models.RowFilter{Column: "nope", Op: models.FilterEq, Value: 1}},
+		{name: "unknown operator", filter: models.RowFilter{Column: "id", Op: "regex", Value: "a"}},
+		{name: "comparison without value", filter: models.RowFilter{Column: "id", Op: models.FilterEq}},
+		{name: "pattern not a string", filter: models.RowFilter{Column: "id", Op: models.FilterLike, Value: 1}},
+		{name: "in not a list", filter: models.RowFilter{Column: "id", Op: models.FilterIn, Value: 1}},
+		{name: "between one bound", filter: models.RowFilter{Column: "id", Op: models.FilterBetween, Value: []any{1}}},
+		{name: "between null bound", filter: models.RowFilter{Column: "id", Op: models.FilterBetween, Value: []any{1, nil}}},
+		{name: "error in nested group", filter: models.RowFilter{Or: []models.RowFilter{
+			{Column: "id", Op: models.FilterIsNull},
+			{Column: "nope", Op: models.FilterIsNull},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFilterBuilder(columns).compile(tt.filter)
+			if !errors.Is(err, models.ErrInvalidFilter) {
+				t.Errorf("compile() error = %v, want %v", err, models.ErrInvalidFilter)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GetFunctions lists the names of the functions that can be called from
+// SQL, leaving out the ones implementing operators, type input/output and
+// triggers.
+func GetFunctions(ctx context.Context, db *pgxpool.Pool) (*models.ApiResponse, error) {
+	query := `
+		SELECT DISTINCT p.proname
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname <> 'information_schema'
+		AND n.nspname NOT LIKE 'pg\_toast%'
+		AND p.prorettype NOT IN ('internal'::regtype, 'cstring'::regtype, 'trigger'::regtype, 'event_trigger'::regtype, 'language_handler'::regtype)
+		AND NOT EXISTS (SELECT 1 FROM pg_operator o WHERE o.oprcode = p.oid)
+		ORDER BY p.proname
+	`
+
+	rows, err := db.Query(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	functions := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		functions = append(functions, name)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    functions,
+	}, nil
+}
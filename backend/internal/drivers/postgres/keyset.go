@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// rowsCursor is what the opaque cursors handed to clients hold: the values
+// of the order columns of the row a page starts after, or ends before when
+// Before is set. Order records the order the values belong to, so a cursor
+// is not reused with a different sort.
+type rowsCursor struct {
+	Before bool   `json:"b,omitempty"`
+	Order  string `json:"o"`
+	Values []any  `json:"v"`
+}
+
+func encodeCursor(terms []orderTerm, row map[string]any, before bool) (string, error) {
+	cursor := rowsCursor{Before: before, Order: orderSignature(terms), Values: make([]any, len(terms))}
+	for i, t := range terms {
+		cursor.Values[i] = row[t.Column]
+	}
+
+	doc, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(doc), nil
+}
+
+func decodeCursor(raw string, terms []orderTerm) (*rowsCursor, error) {
+	doc, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, err)
+	}
+
+	var cursor rowsCursor
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, err)
+	}
+
+	if cursor.Order != orderSignature(terms) || len(cursor.Values) != len(terms) {
+		return nil, fmt.Errorf("%w: the cursor was made for another sort", models.ErrInvalidCursor)
+	}
+
+	return &cursor, nil
+}
+
+func orderSignature(terms []orderTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = fmt.Sprintf("%s:%t:%t", t.Column, t.Desc, t.NullsFirst)
+	}
+	return strings.Join(parts, ",")
+}
+
+// keysetCondition builds the condition keeping the rows after the cursor,
+// or before it, in the order of terms. Values are added to b as parameters.
+func keysetCondition(terms []orderTerm, cursor *rowsCursor, b *filterBuilder) string {
+	if cond, ok := rowComparison(terms, cursor, b); ok {
+		return cond
+	}
+
+	// Each value becomes a parameter once, the first time it is used.
+	placeholders := make([]string, len(terms))
+	placeholder := func(i int) string {
+		if placeholders[i] == "" {
+			placeholders[i] = b.param(cursor.Values[i])
+		}
+		return placeholders[i]
+	}
+
+	// (a beyond va) OR (a = va AND b beyond vb) OR ...
+	var (
+		disjuncts []string
+		equal     []string
+	)
+	for i, t := range terms {
+		col := pgx.Identifier{t.Column}.Sanitize()
+
+		if cond := beyond(t, col, cursor.Values[i] == nil, cursor.Before, func() string { return placeholder(i) }); cond != "" {
+			disjuncts = append(disjuncts, "("+strings.Join(append(equal[:len(equal):len(equal)], cond), " AND ")+")")
+		}
+
+		if i == len(terms)-1 {
+			break
+		}
+		if cursor.Values[i] == nil {
+			equal = append(equal, col+" IS NULL")
+		} else {
+			equal = append(equal, fmt.Sprintf("%s = %s", col, placeholder(i)))
+		}
+	}
+
+	if len(disjuncts) == 0 {
+		return "FALSE"
+	}
+
+	return "(" + strings.Join(disjuncts, " OR ") + ")"
+}
+
+// rowComparison compares all the order columns at once, as in
+// (a, b) > ($1, $2), which an index on them can serve. It only applies
+// when every column is sorted the same way and neither the columns nor
+// the cursor values can be NULL.
+func rowComparison(terms []orderTerm, cursor *rowsCursor, b *filterBuilder) (string, bool) {
+	for i, t := range terms {
+		if t.Nullable || t.Desc != terms[0].Desc || cursor.Values[i] == nil {
+			return "", false
+		}
+	}
+
+	cols := make([]string, len(terms))
+	for i, t := range terms {
+		cols[i] = pgx.Identifier{t.Column}.Sanitize()
+	}
+
+	placeholders := make([]string, len(terms))
+	for i := range terms {
+		placeholders[i] = b.param(cursor.Values[i])
+	}
+
+	op := ">"
+	if terms[0].Desc != cursor.Before {
+		op = "<"
+	}
+
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), op, strings.Join(placeholders, ", ")), true
+}
+
+// beyond builds the condition keeping the values of col coming after the
+// cursor value in its order (before it when before is set), or "" when
+// none can. value returns the placeholder of the cursor value unless it is
+// NULL.
+func beyond(t orderTerm, col string, null bool, before bool, value func() string) string {
+	desc, nullsFirst := t.Desc != before, t.NullsFirst != before
+
+	if null {
+		if nullsFirst {
+			return col + " IS NOT NULL"
+		}
+		return ""
+	}
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	cond := fmt.Sprintf("%s %s %s", col, op, value())
+	if !nullsFirst && t.Nullable {
+		cond = fmt.Sprintf("(%s OR %s IS NULL)", cond, col)
+	}
+
+	return cond
+}
@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	var (
+		id       = orderTerm{Column: "id"}
+		idDesc   = orderTerm{Column: "id", Desc: true, NullsFirst: true}
+		a        = orderTerm{Column: "a"}
+		aDesc    = orderTerm{Column: "a", Desc: true, NullsFirst: true}
+		nullable = orderTerm{Column: "a", Nullable: true}
+	)
+
+	tests := []struct {
+		name   string
+		terms  []orderTerm
+		cursor rowsCursor
+		want   string
+		params []any
+	}{
+		{
+			name:   "single column after",
+			terms:  []orderTerm{id},
+			cursor: rowsCursor{Values: []any{5}},
+			want:   `("id") > ($1)`,
+			params: []any{5},
+		},
+		{
+			name:   "single column before",
+			terms:  []orderTerm{id},
+			cursor: rowsCursor{Before: true, Values: []any{5}},
+			want:   `("id") < ($1)`,
+			params: []any{5},
+		},
+		{
+			name:   "row comparison descending",
+			terms:  []orderTerm{aDesc, idDesc},
+			cursor: rowsCursor{Values: []any{"x", 5}},
+			want:   `("a", "id") < ($1, $2)`,
+			params: []any{"x", 5},
+		},
+		{
+			name:   "mixed directions",
+			terms:  []orderTerm{a, idDesc},
+			cursor: rowsCursor{Values: []any{1, 2}},
+			want:   `(("a" > $1) OR ("a" = $1 AND "id" < $2))`,
+			params: []any{1, 2},
+		},
+		{
+			name:   "nullable column",
+			terms:  []orderTerm{nullable, id},
+			cursor: rowsCursor{Values: []any{3, 7}},
+			want:   `((("a" > $1 OR "a" IS NULL)) OR ("a" = $1 AND "id" > $2))`,
+			params: []any{3, 7},
+		},
+		{
+			name:   "null cursor value, nulls last",
+			terms:  []orderTerm{nullable, id},
+			cursor: rowsCursor{Values: []any{nil, 7}},
+			want:   `(("a" IS NULL AND "id" > $1))`,
+			params: []any{7},
+		},
+		{
+			name:   "null cursor value, before",
+			terms:  []orderTerm{nullable, id},
+			cursor: rowsCursor{Before: true, Values: []any{nil, 7}},
+			want:   `(("a" IS NOT NULL) OR ("a" IS NULL AND "id" < $1))`,
+			params: []any{7},
+		},
+		{
+			name:   "nothing after the last null",
+			terms:  []orderTerm{nullable},
+			cursor: rowsCursor{Values: []any{nil}},
+			want:   `FALSE`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &filterBuilder{}
+			got := keysetCondition(tt.terms, &tt.cursor, b)
+			if got != tt.want {
+				t.Errorf("keysetCondition() = %s, want %s", got, tt.want)
+			}
+
+			var params []any
+			for _, p := range b.params {
+				params = append(params, p.Value)
+			}
+			if !reflect.DeepEqual(params, tt.params) {
+				t.Errorf("params = %v, want %v", params, tt.params)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	terms := []orderTerm{{Column: "name", Desc: true, NullsFirst: true, Nullable: true}, {Column: "id"}}
+
+	tests := []struct {
+		name   string
+		row    map[string]any
+		before bool
+		want   []any
+	}{
+		{
+			name: "after",
+			row:  map[string]any{"id": 42, "name": "ada", "other": true},
+			want: []any{"ada", json.Number("42")},
+		},
+		{
+			name:   "before",
+			row:    map[string]any{"id": 1, "name": "x"},
+			before: true,
+			want:   []any{"x", json.Number("1")},
+		},
+		{
+			name: "null value",
+			row:  map[string]any{"id": 7, "name": nil},
+			want: []any{nil, json.Number("7")},
+		},
+		{
+			name: "bytea value",
+			row:  map[string]any{"id": 3, "name": jsonValue([]byte{0xde, 0xad})},
+			want: []any{`\xdead`, json.Number("3")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := encodeCursor(terms, tt.row, tt.before)
+			if err != nil {
+				t.Fatalf("encodeCursor() error = %v", err)
+			}
+
+			cursor, err := decodeCursor(raw, terms)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if cursor.Before != tt.before {
+				t.Errorf("Before = %t, want %t", cursor.Before, tt.before)
+			}
+			if !reflect.DeepEqual(cursor.Values, tt.want) {
+				t.Errorf("Values = %#v, want %#v", cursor.Values, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorBytea(t *testing.T) {
+	terms := []orderTerm{{Column: "key"}}
+
+	raw, err := encodeCursor(terms, map[string]any{"key": jsonValue([]byte{0x00, 0xff, 0x10})}, false)
+	if err != nil {
+		t.Fatalf("encodeCursor() error = %v", err)
+	}
+
+	cursor, err := decodeCursor(raw, terms)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+
+	// The value reaches the server as the bytes it was read as.
+	got, err := encodeParam(cursor.Values[0], "bytea")
+	if err != nil {
+		t.Fatalf("encodeParam() error = %v", err)
+	}
+	if want := `\x00ff10`; string(got) != want {
+		t.Errorf("encodeParam() = %s, want %s", got, want)
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	terms := []orderTerm{{Column: "id"}}
+
+	valid, err := encodeCursor(terms, map[string]any{"id": 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		raw   string
+		terms []orderTerm
+	}{
+		{name: "not base64", raw: "%%%", terms: terms},
+		{name: "not json", raw: base64.RawURLEncoding.EncodeToString([]byte("{")), terms: terms},
+		{name: "other column", raw: valid, terms: []orderTerm{{Column: "name"}}},
+		{name: "other direction", raw: valid, terms: []orderTerm{{Column: "id", Desc: true, NullsFirst: true}}},
+		{name: "more columns", raw: valid, terms: []orderTerm{{Column: "id"}, {Column: "name"}}},
+		{
+			name:  "values missing",
+			raw:   base64.RawURLEncoding.EncodeToString([]byte(`{"o":"id:false:false","v":[]}`)),
+			terms: terms,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.raw, tt.terms)
+			if !errors.Is(err, models.ErrInvalidCursor) {
+				t.Errorf("decodeCursor() error = %v, want %v", err, models.ErrInvalidCursor)
+			}
+		})
+	}
+}
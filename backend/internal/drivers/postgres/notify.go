@@ -0,0 +1,438 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// listenCloseTimeout bounds how long closing the LISTEN connection may
+	// take.
+	listenCloseTimeout = 5 * time.Second
+
+	// subscriberBuffer is how many notifications may wait for a subscriber
+	// before it is dropped for being too slow.
+	subscriberBuffer = 256
+)
+
+var (
+	errListenerClosed = errors.New("the listen connection was closed")
+	errSlowSubscriber = errors.New("notifications arrived faster than they could be sent")
+)
+
+// Listener shares a single connection of its own, outside the pool, between
+// all the subscribers: it LISTENs on every channel at least one of them
+// listens on and hands each notification to the subscribers of its
+// channel. The connection is opened with the first subscriber and closed
+// with the last one.
+type Listener struct {
+	db             *pgxpool.Pool
+	maxSubscribers int
+
+	mu   sync.Mutex
+	conn *listenConn
+}
+
+// NewListener returns a listener allowing up to maxSubscribers subscribers
+// at once (no limit when zero).
+func NewListener(db *pgxpool.Pool, maxSubscribers int) *Listener {
+	return &Listener{db: db, maxSubscribers: maxSubscribers}
+}
+
+// Listen subscribes to channels and hands every notification to sink until
+// ctx is done or commands is closed. Commands add or remove channels along
+// the way; one that fails is reported to sink and the subscriber keeps
+// going.
+func (l *Listener) Listen(ctx context.Context, channels []string, commands <-chan models.ListenCommand, sink models.NotificationSink) error {
+	c, sub, err := l.join(ctx)
+	if err != nil {
+		return err
+	}
+	defer l.leave(c, sub)
+
+	for _, channel := range channels {
+		if err := c.listen(sub, channel); err != nil {
+			return err
+		}
+	}
+
+	if err := sink.Listening(c.channelsOf(sub)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case n := <-sub.notifications:
+			if err := sink.Notification(n); err != nil {
+				return err
+			}
+
+		case cmd, ok := <-commands:
+			if !ok {
+				return nil
+			}
+
+			if err := c.apply(sub, cmd); err != nil {
+				if err := sink.Error(err); err != nil {
+					return err
+				}
+			} else if err := sink.Listening(c.channelsOf(sub)); err != nil {
+				return err
+			}
+
+		case <-sub.dropped:
+			return errSlowSubscriber
+
+		case <-c.done:
+			return c.err
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// join adds a subscriber, opening the connection if there is none yet or
+// the last one failed.
+func (l *Listener) join(ctx context.Context) (*listenConn, *subscriber, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil && l.conn.closed() {
+		l.conn = nil
+	}
+
+	if l.conn == nil {
+		c, err := openListenConn(ctx, l.db)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.conn = c
+	}
+
+	c := l.conn
+	if l.maxSubscribers > 0 && c.count() >= l.maxSubscribers {
+		return nil, nil, models.ErrTooManySubscribers
+	}
+
+	return c, c.add(), nil
+}
+
+// leave removes a subscriber and its channels, closing the connection when
+// it was the last one.
+func (l *Listener) leave(c *listenConn, sub *subscriber) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c.remove(sub) > 0 {
+		for _, channel := range c.channelsOf(sub) {
+			c.unlisten(sub, channel)
+		}
+		return
+	}
+
+	if l.conn == c {
+		l.conn = nil
+	}
+	close(c.requests)
+}
+
+// Notify sends a notification on channel.
+func Notify(ctx context.Context, db *pgxpool.Pool, req models.NotifyRequest) (*models.ApiResponse, error) {
+	if _, err := db.Exec(ctx, `SELECT pg_notify($1, $2)`, req.Channel, req.Payload); err != nil {
+		return nil, err
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "notified",
+	}, nil
+}
+
+type subscriber struct {
+	channels      []string
+	notifications chan models.Notification
+	dropped       chan struct{}
+	isDropped     bool
+}
+
+// listenRequest asks the goroutine owning the connection to LISTEN on a
+// channel or to UNLISTEN from it.
+type listenRequest struct {
+	channel string
+	listen  bool
+	reply   chan error
+}
+
+// listenConn is the connection shared by the subscribers. Only its run
+// goroutine uses the connection; subscribers go through requests.
+type listenConn struct {
+	conn     *pgx.Conn
+	requests chan listenRequest
+	done     chan struct{}
+	err      error
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	// listeners counts the subscribers of each channel listened on; only
+	// used by run.
+	listeners map[string]int
+}
+
+func openListenConn(ctx context.Context, db *pgxpool.Pool) (*listenConn, error) {
+	config := db.Config().ConnConfig.Copy()
+
+	// Waiting for notifications is interrupted whenever a request arrives.
+	// A deadline does that without a cancel request or closing the
+	// connection.
+	config.BuildContextWatcherHandler = func(pgConn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.DeadlineContextWatcherHandler{Conn: pgConn.Conn()}
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &listenConn{
+		conn:        conn,
+		requests:    make(chan listenRequest),
+		done:        make(chan struct{}),
+		subscribers: map[*subscriber]struct{}{},
+		listeners:   map[string]int{},
+	}
+
+	go c.run()
+
+	return c, nil
+}
+
+// run waits for notifications and requests until requests is closed or the
+// connection fails, then closes the connection.
+func (c *listenConn) run() {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), listenCloseTimeout)
+		defer cancel()
+
+		c.conn.Close(ctx)
+		close(c.done)
+	}()
+
+	ctx := context.Background()
+	for {
+		n, req, err := c.wait(ctx)
+		if n != nil {
+			c.dispatch(models.Notification{
+				Channel:    n.Channel,
+				Payload:    n.Payload,
+				PID:        n.PID,
+				ReceivedAt: time.Now(),
+			})
+		}
+
+		if req != nil {
+			req.reply <- c.exec(ctx, *req)
+			continue
+		}
+
+		if errors.Is(err, errListenerClosed) || (err != nil && n == nil) {
+			c.err = err
+			return
+		}
+	}
+}
+
+// wait blocks until a notification or a request arrives, whichever comes
+// first. Both may be returned when they arrive together.
+func (c *listenConn) wait(ctx context.Context) (*pgconn.Notification, *listenRequest, error) {
+	waitCtx, cancel := context.WithCancel(ctx)
+
+	var (
+		req    *listenRequest
+		closed bool
+		done   = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+
+		select {
+		case r, ok := <-c.requests:
+			if ok {
+				req = &r
+			} else {
+				closed = true
+			}
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	n, err := c.conn.WaitForNotification(waitCtx)
+	cancel()
+	<-done
+
+	if closed {
+		return n, nil, errListenerClosed
+	}
+
+	return n, req, err
+}
+
+// exec LISTENs on the channel of req for its first subscriber and
+// UNLISTENs when its last one leaves.
+func (c *listenConn) exec(ctx context.Context, req listenRequest) error {
+	count := c.listeners[req.channel]
+
+	if req.listen {
+		if count == 0 {
+			if _, err := c.conn.Exec(ctx, "LISTEN "+pgx.Identifier{req.channel}.Sanitize()); err != nil {
+				return err
+			}
+		}
+		c.listeners[req.channel] = count + 1
+		return nil
+	}
+
+	if count > 1 {
+		c.listeners[req.channel] = count - 1
+		return nil
+	}
+
+	delete(c.listeners, req.channel)
+	_, err := c.conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{req.channel}.Sanitize())
+	return err
+}
+
+// dispatch hands n to the subscribers of its channel. One whose buffer is
+// full is dropped rather than holding up the others.
+func (c *listenConn) dispatch(n models.Notification) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for sub := range c.subscribers {
+		if sub.isDropped || !slices.Contains(sub.channels, n.Channel) {
+			continue
+		}
+
+		select {
+		case sub.notifications <- n:
+		default:
+			sub.isDropped = true
+			close(sub.dropped)
+		}
+	}
+}
+
+// request hands req to run and waits for the outcome.
+func (c *listenConn) request(req listenRequest) error {
+	req.reply = make(chan error, 1)
+
+	select {
+	case c.requests <- req:
+	case <-c.done:
+		return c.err
+	}
+
+	select {
+	case err := <-req.reply:
+		return err
+	case <-c.done:
+		return c.err
+	}
+}
+
+func (c *listenConn) apply(sub *subscriber, cmd models.ListenCommand) error {
+	switch cmd.Action {
+	case models.ListenActionListen:
+		return c.listen(sub, cmd.Channel)
+	case models.ListenActionUnlisten:
+		return c.unlisten(sub, cmd.Channel)
+	default:
+		return errors.New("action must be listen or unlisten")
+	}
+}
+
+func (c *listenConn) listen(sub *subscriber, channel string) error {
+	if channel == "" {
+		return errors.New("channel is required")
+	}
+	if slices.Contains(c.channelsOf(sub), channel) {
+		return nil
+	}
+
+	if err := c.request(listenRequest{channel: channel, listen: true}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	sub.channels = append(sub.channels, channel)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *listenConn) unlisten(sub *subscriber, channel string) error {
+	if !slices.Contains(c.channelsOf(sub), channel) {
+		return nil
+	}
+
+	c.mu.Lock()
+	sub.channels = slices.DeleteFunc(slices.Clone(sub.channels), func(ch string) bool { return ch == channel })
+	c.mu.Unlock()
+
+	return c.request(listenRequest{channel: channel, listen: false})
+}
+
+func (c *listenConn) channelsOf(sub *subscriber) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(sub.channels)
+}
+
+func (c *listenConn) add() *subscriber {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub := &subscriber{
+		notifications: make(chan models.Notification, subscriberBuffer),
+		dropped:       make(chan struct{}),
+	}
+	c.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// remove takes sub out of the subscribers and returns how many remain.
+func (c *listenConn) remove(sub *subscriber) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subscribers, sub)
+	return len(c.subscribers)
+}
+
+func (c *listenConn) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.subscribers)
+}
+
+func (c *listenConn) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// encodeParam converts a JSON-decoded value into the text representation
+// Postgres expects for typeName. A nil result is sent as NULL.
+//
+// Objects and arrays are sent as JSON unless the target is an array type, in
+// which case they become an array literal. For json and jsonb, the value is
+// the JSON value itself: a string is stored as a JSON string even when it
+// holds JSON. For bytea, strings are taken as the server reads them: hex
+// with a \x prefix, the form rows are read in. Base64 must come wrapped in
+// a {"base64": ...} object.
+func encodeParam(value any, typeName string) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch {
+	case isArrayType(typeName):
+		if list, ok := value.([]any); ok {
+			lit, err := arrayLiteral(list)
+			return []byte(lit), err
+		}
+
+	case typeName == "json" || typeName == "jsonb":
+		return json.Marshal(value)
+
+	case typeName == "bytea":
+		if obj, ok := value.(map[string]any); ok {
+			return byteaValue(obj)
+		}
+	}
+
+	s, err := textValue(value)
+	return []byte(s), err
+}
+
+// byteaValue decodes a {"base64": ...} object into a hex bytea value.
+func byteaValue(obj map[string]any) ([]byte, error) {
+	encoded, ok := obj["base64"].(string)
+	if !ok || len(obj) != 1 {
+		return nil, errors.New(`bytea values must be \x hex strings or {"base64": ...} objects`)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 bytea value: %w", err)
+	}
+
+	return []byte(byteaHex(b)), nil
+}
+
+// byteaHex is the hex text form of a bytea value, such as \xdead.
+func byteaHex(b []byte) string {
+	return `\x` + hex.EncodeToString(b)
+}
+
+func textValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// arrayLiteral builds a Postgres array literal such as {1,"a b",NULL} from
+// a (possibly nested) JSON array.
+func arrayLiteral(list []any) (string, error) {
+	var sb strings.Builder
+	sb.WriteByte('{')
+
+	for i, elem := range list {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		switch v := elem.(type) {
+		case nil:
+			sb.WriteString("NULL")
+		case []any:
+			lit, err := arrayLiteral(v)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(lit)
+		default:
+			s, err := textValue(v)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte('"')
+			sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s))
+			sb.WriteByte('"')
+		}
+	}
+
+	sb.WriteByte('}')
+	return sb.String(), nil
+}
+
+func isArrayType(typeName string) bool {
+	return strings.HasPrefix(typeName, "_") || strings.HasSuffix(typeName, "[]")
+}
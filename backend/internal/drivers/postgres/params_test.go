@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		typeName string
+		want     string
+		null     bool
+		wantErr  bool
+	}{
+		{name: "null", value: nil, typeName: "int4", null: true},
+		{name: "number", value: json.Number("12"), typeName: "int4", want: "12"},
+		{name: "float", value: 1.5, typeName: "numeric", want: "1.5"},
+		{name: "bool", value: true, typeName: "bool", want: "true"},
+		{name: "text", value: "hello", typeName: "text", want: "hello"},
+		{name: "object as text", value: map[string]any{"a": "b"}, typeName: "text", want: `{"a":"b"}`},
+
+		{name: "jsonb object", value: map[string]any{"a": json.Number("1")}, typeName: "jsonb", want: `{"a":1}`},
+		{name: "jsonb list", value: []any{json.Number("1"), "a"}, typeName: "jsonb", want: `[1,"a"]`},
+		{name: "jsonb number", value: json.Number("123"), typeName: "jsonb", want: `123`},
+		{name: "jsonb numeric string stays a string", value: "123", typeName: "jsonb", want: `"123"`},
+		{name: "json string holding json stays a string", value: `{"a":1}`, typeName: "json", want: `"{\"a\":1}"`},
+		{name: "jsonb null inside", value: map[string]any{"a": nil}, typeName: "jsonb", want: `{"a":null}`},
+
+		{name: "bytea hex", value: `\xdead`, typeName: "bytea", want: `\xdead`},
+		{name: "bytea base64 string is text", value: "3q0=", typeName: "bytea", want: "3q0="},
+		{name: "bytea base64 object", value: map[string]any{"base64": "3q0="}, typeName: "bytea", want: `\xdead`},
+		{name: "bytea empty base64", value: map[string]any{"base64": ""}, typeName: "bytea", want: `\x`},
+		{name: "bytea invalid base64", value: map[string]any{"base64": "!!"}, typeName: "bytea", wantErr: true},
+		{name: "bytea base64 not a string", value: map[string]any{"base64": json.Number("1")}, typeName: "bytea", wantErr: true},
+		{name: "bytea other object", value: map[string]any{"hex": "dead"}, typeName: "bytea", wantErr: true},
+		{name: "bytea extra keys", value: map[string]any{"base64": "3q0=", "x": "y"}, typeName: "bytea", wantErr: true},
+
+		{name: "array", value: []any{json.Number("1"), json.Number("2")}, typeName: "_int4", want: `{"1","2"}`},
+		{name: "array by suffix", value: []any{"a"}, typeName: "text[]", want: `{"a"}`},
+		{name: "array literal string", value: "{1,2}", typeName: "_int4", want: "{1,2}"},
+		{name: "list for scalar type", value: []any{json.Number("1")}, typeName: "text", want: `[1]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeParam(tt.value, tt.typeName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("encodeParam() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("encodeParam() error = %v", err)
+			}
+			if tt.null {
+				if got != nil {
+					t.Errorf("encodeParam() = %q, want NULL", got)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("encodeParam() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArrayLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		list []any
+		want string
+	}{
+		{name: "empty", list: []any{}, want: `{}`},
+		{name: "numbers", list: []any{json.Number("1"), 2.5}, want: `{"1","2.5"}`},
+		{name: "null element", list: []any{"a", nil}, want: `{"a",NULL}`},
+		{name: "quotes and backslashes", list: []any{`a"b\c`}, want: `{"a\"b\\c"}`},
+		{name: "commas and braces", list: []any{"a,b", "{c}"}, want: `{"a,b","{c}"}`},
+		{name: "nested", list: []any{[]any{"1", "2"}, []any{"3", nil}}, want: `{{"1","2"},{"3",NULL}}`},
+		{name: "object element", list: []any{map[string]any{"k": "v"}}, want: `{"{\"k\":\"v\"}"}`},
+		{name: "bool element", list: []any{true, false}, want: `{"true","false"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := arrayLiteral(tt.list)
+			if err != nil {
+				t.Fatalf("arrayLiteral() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("arrayLiteral() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,24 +2,546 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/notices"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunQuery(db *pgxpool.Pool, query string) (*models.ApiResponse, error) {
-	_, err := db.Exec(
-		context.Background(),
-		query,
-	)
+func RunQuery(ctx context.Context, db *pgxpool.Pool, req models.QueryRequest, readOnly bool) (*models.ApiResponse, error) {
+	statements := SplitStatements(req.Query)
+	if readOnly {
+		if err := checkReadOnlyScript(statements); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Mode = scriptMode(req.Mode, statements)
+	result := &models.ScriptResult{
+		Mode:       req.Mode,
+		Statements: make([]models.StatementResult, len(statements)),
+	}
+	for i, stmt := range statements {
+		result.Statements[i].Statement = stmt
+	}
+	offsets := statementOffsets(req.Query, statements)
+
+	collector := notices.Listen(conn.Conn().PgConn())
+	defer collector.Stop()
+
+	run := func(conn *pgx.Conn, i int) error {
+		res := &result.Statements[i]
+
+		// Notices raised before the statement, by BEGIN or SET, are dropped.
+		collector.Take()
+
+		res.StartedAt = time.Now()
+		qr, err := runStatement(ctx, conn, res.Statement, req.Params, hints, req.MaxRows)
+		res.DurationMs = durationMs(time.Since(res.StartedAt))
+		res.Notices = collector.Take()
+
+		if err != nil {
+			failStatement(res, err, offsets[i])
+			result.Failed++
+			return readOnlyError(err)
+		}
+
+		res.QueryResult = qr
+		return nil
+	}
+
+	begin := func() (pgx.Tx, error) {
+		return beginWithTimeout(ctx, conn.Conn(), readOnly, req.TimeoutMs)
+	}
+
+	if req.Mode == models.QueryModeContinue {
+		if !readOnly {
+			// Statements such as VACUUM can't run inside a transaction, so
+			// the timeout is set for the session instead.
+			reset, err := setStatementTimeout(ctx, conn, req.TimeoutMs)
+			if err != nil {
+				return nil, err
+			}
+			defer reset()
+		}
+
+		for i := range result.Statements {
+			var err error
+			if readOnly {
+				// Every statement gets its own read-only transaction, so a
+				// failure doesn't abort the next ones.
+				tx, txErr := begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				err = run(tx.Conn(), i)
+				tx.Rollback(ctx)
+			} else {
+				err = run(conn.Conn(), i)
+			}
+
+			if errors.Is(err, models.ErrReadOnly) {
+				return nil, err
+			}
+		}
+	} else {
+		tx, err := begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		for i := range result.Statements {
+			if result.Failed > 0 {
+				result.Statements[i].Skipped = true
+				continue
+			}
+			if err := run(tx.Conn(), i); errors.Is(err, models.ErrReadOnly) {
+				return nil, err
+			}
+		}
+
+		if result.Failed > 0 || readOnly {
+			if err := tx.Rollback(ctx); err != nil {
+				return nil, err
+			}
+			result.RolledBack = result.Failed > 0
+		} else if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	message := "success"
+	switch {
+	case result.RolledBack:
+		message = "transaction rolled back"
+	case result.Failed > 0:
+		message = "completed with errors"
+	}
+
+	return &models.ApiResponse{
+		Status:    http.StatusOK,
+		Message:   message,
+		Data:      result,
+		Truncated: truncated(result),
+	}, nil
+}
+
+// scriptMode returns the mode statements run in when the request asked for
+// mode, which may be empty.
+func scriptMode(mode string, statements []string) string {
+	switch {
+	case mode != "":
+		return mode
+	case len(statements) == 1:
+		return models.QueryModeContinue
+	default:
+		return models.QueryModeTransaction
+	}
+}
+
+// failStatement records the error of a statement. The position of a server
+// error is moved from the statement to the whole script.
+func failStatement(res *models.StatementResult, err error, offset int) {
+	pgerror.ShiftPosition(err, offset)
+	res.Error = err.Error()
+	res.ErrorDetails = pgerror.Details(err)
+}
+
+// truncated reports whether a row limit cut any result set of the script.
+func truncated(result *models.ScriptResult) bool {
+	return slices.ContainsFunc(result.Statements, func(stmt models.StatementResult) bool {
+		return stmt.QueryResult != nil && stmt.Truncated
+	})
+}
+
+// StreamQuery runs a single statement, handing rows to sink as they arrive
+// instead of keeping the whole result set in memory. The statement runs in
+// its own transaction so the timeout can be applied to it.
+func StreamQuery(ctx context.Context, db *pgxpool.Pool, req models.QueryRequest, readOnly bool, sink models.RowSink) (*models.ApiResponse, error) {
+	statements := SplitStatements(req.Query)
+	if len(statements) != 1 {
+		return nil, errors.New("streaming expects exactly one statement")
+	}
+	if readOnly {
+		if err := checkReadOnlyScript(statements); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), readOnly, req.TimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result := &models.QueryResult{}
+	if err := streamStatement(ctx, tx.Conn(), statements[0], req.Params, hints, req.MaxRows, result, sink); err != nil {
+		pgerror.ShiftPosition(err, statementOffsets(req.Query, statements)[0])
+		return nil, readOnlyError(err)
+	}
+
+	if !readOnly {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.ApiResponse{
+		Status:    http.StatusOK,
+		Message:   "success",
+		Data:      result,
+		Truncated: result.Truncated,
+	}, nil
+}
+
+// DescribeQuery prepares every statement of the script without running it and
+// reports the parameter types inferred by the server and the result columns.
+func DescribeQuery(ctx context.Context, db *pgxpool.Pool, req models.QueryRequest) (*models.ApiResponse, error) {
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Outside of a transaction, as a statement failing to prepare would
+	// abort it for the next ones.
+	reset, err := setStatementTimeout(ctx, conn, req.TimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer reset()
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := SplitStatements(req.Query)
+	descriptions := make([]models.StatementDescription, len(statements))
+
+	for i, stmt := range statements {
+		descriptions[i] = describeStatement(ctx, conn.Conn(), stmt, hints)
+	}
+
 	return &models.ApiResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    query,
+		Data:    descriptions,
 	}, nil
 }
+
+func describeStatement(ctx context.Context, conn *pgx.Conn, query string, hints []uint32) models.StatementDescription {
+	desc := models.StatementDescription{Statement: query}
+
+	sd, err := conn.PgConn().Prepare(ctx, "", query, hints)
+	if err != nil {
+		desc.Error = err.Error()
+		return desc
+	}
+
+	names, err := typeNames(ctx, conn, sd.ParamOIDs)
+	if err != nil {
+		desc.Error = err.Error()
+		return desc
+	}
+
+	desc.Params = make([]models.QueryParamType, len(sd.ParamOIDs))
+	for i, oid := range sd.ParamOIDs {
+		desc.Params[i] = models.QueryParamType{Position: i + 1, TypeOID: oid, TypeName: names[oid]}
+	}
+
+	desc.Columns = queryColumns(sd.Fields)
+	if err := resolveTypeNames(ctx, conn, desc.Columns); err != nil {
+		desc.Error = err.Error()
+	}
+
+	return desc
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// runStatement executes a single statement and collects its result set, if
+// any, together with the command tag reported by the server. At most maxRows
+// rows are kept, when maxRows is positive.
+func runStatement(ctx context.Context, conn *pgx.Conn, query string, params []models.QueryParam, hints []uint32, maxRows int) (*models.QueryResult, error) {
+	result := &models.QueryResult{}
+
+	err := streamStatement(ctx, conn, query, params, hints, maxRows, result, collector{result})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// collector is a RowSink that keeps every row in memory.
+type collector struct {
+	result *models.QueryResult
+}
+
+func (c collector) Columns(columns []models.QueryColumn) error {
+	c.result.Rows = [][]any{}
+	return nil
+}
+
+func (c collector) Row(values []any) error {
+	c.result.Rows = append(c.result.Rows, values)
+	return nil
+}
+
+// streamStatement executes a single statement, handing every row to sink as
+// it is read and filling result with the columns and command tag. Parameters
+// are always sent in text format so the server parses them according to the
+// types it inferred (or the ones hinted by the caller). When maxRows is
+// positive, rows past it are skipped and the result is marked truncated.
+func streamStatement(ctx context.Context, conn *pgx.Conn, query string, params []models.QueryParam, hints []uint32, maxRows int, result *models.QueryResult, sink models.RowSink) error {
+	sd, err := conn.PgConn().Prepare(ctx, "", query, hints)
+	if err != nil {
+		return err
+	}
+
+	if len(sd.ParamOIDs) > len(params) {
+		return fmt.Errorf("statement expects %d parameters, got %d", len(sd.ParamOIDs), len(params))
+	}
+
+	names, err := typeNames(ctx, conn, sd.ParamOIDs)
+	if err != nil {
+		return err
+	}
+
+	values := make([][]byte, len(sd.ParamOIDs))
+	for i, oid := range sd.ParamOIDs {
+		values[i], err = encodeParam(params[i].Value, names[oid])
+		if err != nil {
+			return fmt.Errorf("parameter $%d: %w", i+1, err)
+		}
+	}
+
+	resultFormats := make([]int16, len(sd.Fields))
+	for i, fd := range sd.Fields {
+		resultFormats[i] = conn.TypeMap().FormatCodeForOID(fd.DataTypeOID)
+	}
+
+	// Column type names are resolved before running the statement, since the
+	// connection is busy while rows are being read.
+	result.Columns = queryColumns(sd.Fields)
+	if err := resolveTypeNames(ctx, conn, result.Columns); err != nil {
+		return err
+	}
+
+	rows := pgx.RowsFromResultReader(
+		conn.TypeMap(),
+		conn.PgConn().ExecPrepared(ctx, "", values, nil, resultFormats),
+	)
+	defer rows.Close()
+
+	if result.Columns != nil {
+		if err := sink.Columns(result.Columns); err != nil {
+			return err
+		}
+	}
+
+	truncated, err := scanRows(rows, sink, maxRows)
+	if err != nil {
+		return err
+	}
+	result.Truncated = truncated
+
+	tag := rows.CommandTag()
+	result.Command = tag.String()
+	result.RowsAffected = tag.RowsAffected()
+
+	return nil
+}
+
+// scanRows reads every row, converting values so they encode cleanly as
+// JSON, and hands them to sink. Once maxRows rows were read (if positive)
+// the rest are discarded and scanRows reports the result as truncated. Rows
+// are closed before returning.
+func scanRows(rows pgx.Rows, sink models.RowSink, maxRows int) (bool, error) {
+	defer rows.Close()
+
+	truncated := false
+	read := 0
+	for rows.Next() {
+		if maxRows > 0 && read >= maxRows {
+			truncated = true
+			break
+		}
+
+		values, err := rows.Values()
+		if err != nil {
+			return false, err
+		}
+
+		for i := range values {
+			values[i] = jsonValue(values[i])
+		}
+
+		if err := sink.Row(values); err != nil {
+			return false, err
+		}
+		read++
+	}
+
+	rows.Close()
+	return truncated, rows.Err()
+}
+
+func queryColumns(fields []pgconn.FieldDescription) []models.QueryColumn {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	columns := make([]models.QueryColumn, len(fields))
+	for i, fd := range fields {
+		columns[i] = models.QueryColumn{Name: fd.Name, TypeOID: fd.DataTypeOID}
+	}
+
+	return columns
+}
+
+// resolveTypeNames fills in the type name of every column.
+func resolveTypeNames(ctx context.Context, conn *pgx.Conn, columns []models.QueryColumn) error {
+	oids := make([]uint32, len(columns))
+	for i, col := range columns {
+		oids[i] = col.TypeOID
+	}
+
+	names, err := typeNames(ctx, conn, oids)
+	if err != nil {
+		return err
+	}
+
+	for i, col := range columns {
+		columns[i].TypeName = names[col.TypeOID]
+	}
+
+	return nil
+}
+
+// typeNames maps type OIDs to their names, asking the server for the ones
+// pgx doesn't know about (enums, domains, extension types). The lookup uses a
+// named statement so it doesn't replace the unnamed one being executed.
+func typeNames(ctx context.Context, conn *pgx.Conn, oids []uint32) (map[uint32]string, error) {
+	names := map[uint32]string{}
+
+	var unknown []uint32
+	for _, oid := range oids {
+		if t, ok := conn.TypeMap().TypeForOID(oid); ok {
+			names[oid] = t.Name
+			continue
+		}
+		unknown = append(unknown, oid)
+	}
+
+	if len(unknown) == 0 {
+		return names, nil
+	}
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT t.oid, format_type(t.oid, NULL) FROM unnest($1::oid[]) AS t(oid)`,
+		pgx.QueryExecModeCacheStatement,
+		unknown,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oid uint32
+		var name string
+		if err := rows.Scan(&oid, &name); err != nil {
+			return nil, err
+		}
+		names[oid] = name
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return names, nil
+}
+
+// paramTypeOIDs resolves the type hints of params into the OIDs passed to
+// the server when preparing statements. Parameters without a hint get OID 0
+// so the server infers their type.
+func paramTypeOIDs(ctx context.Context, conn *pgx.Conn, params []models.QueryParam) ([]uint32, error) {
+	hints := make([]string, len(params))
+	hinted := false
+	for i, p := range params {
+		hints[i] = p.Type
+		hinted = hinted || p.Type != ""
+	}
+
+	if !hinted {
+		return nil, nil
+	}
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT CASE WHEN h.name = '' THEN 0 ELSE coalesce(to_regtype(h.name)::oid, 0) END
+		FROM unnest($1::text[]) WITH ORDINALITY AS h(name, n)
+		ORDER BY h.n`,
+		pgx.QueryExecModeCacheStatement,
+		hints,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	oids := make([]uint32, 0, len(params))
+	for rows.Next() {
+		var oid uint32
+		if err := rows.Scan(&oid); err != nil {
+			return nil, err
+		}
+		if oid == 0 && hints[len(oids)] != "" {
+			return nil, fmt.Errorf("parameter $%d: unknown type %q", len(oids)+1, hints[len(oids)])
+		}
+		oids = append(oids, oid)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return oids, nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// readOnlySQLTransaction is the SQLSTATE raised when a write is attempted
+// in a read-only transaction (or on a hot standby).
+const readOnlySQLTransaction = "25006"
+
+var transactionControl = []string{"abort", "begin", "commit", "end", "release", "rollback", "savepoint", "start"}
+
+// beginReadOnly starts a read-only transaction. A first query runs right
+// away so the server takes the transaction snapshot: from then on the
+// transaction can no longer be switched to read-write. Callers always roll
+// it back, which also reverts any setting changed inside it.
+func beginReadOnly(ctx context.Context, conn *pgx.Conn) (pgx.Tx, error) {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// checkReadOnlyScript rejects statements that would let a script escape the
+// read-only transaction it runs in.
+func checkReadOnlyScript(statements []string) error {
+	for _, stmt := range statements {
+		if slices.Contains(transactionControl, FirstKeyword(stmt)) {
+			return fmt.Errorf("%w: transaction control statements are not allowed", models.ErrReadOnly)
+		}
+	}
+
+	return nil
+}
+
+// readOnlyError wraps err in models.ErrReadOnly when it was raised because a
+// statement tried to write in a read-only transaction.
+func readOnlyError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == readOnlySQLTransaction {
+		return fmt.Errorf("%w: %s", models.ErrReadOnly, pgErr.Message)
+	}
+
+	return err
+}
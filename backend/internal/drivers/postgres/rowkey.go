@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ctidColumn keys the rows of tables with neither a primary key nor a
+// unique constraint on NOT NULL columns. GetRows returns it with the other
+// columns of such tables. A ctid changes whenever the row is updated.
+const ctidColumn = "ctid"
+
+// versionColumn holds the version of each row GetRows returns: the id of
+// the transaction that last wrote it. Updates and deletes only apply to the
+// version they were given.
+const versionColumn = "xmin"
+
+// querier runs queries on a connection or on the pool.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// rowKeyQuery selects the columns identifying a single row of the relation
+// named by $1 and $2, as keyColumns describes; no row when it does not
+// exist. Only ordinary and partitioned tables have a ctid and row versions:
+// other relations, such as views and foreign tables, get no row key.
+const rowKeyQuery = `
+	SELECT CASE
+		WHEN r.relkind NOT IN ('r', 'p') THEN '{}'
+		ELSE COALESCE(key.columns, ARRAY['` + ctidColumn + `'])
+	END AS columns
+	FROM pg_class r
+	LEFT JOIN LATERAL (
+		SELECT array_agg(a.attname::text ORDER BY k.n) AS columns
+		FROM pg_index i
+		CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+		WHERE i.indrelid = r.oid
+		AND i.indisunique
+		AND i.indisvalid
+		AND i.indpred IS NULL
+		AND i.indexprs IS NULL
+		GROUP BY i.indexrelid, i.indisprimary
+		HAVING bool_and(a.attnotnull)
+		ORDER BY i.indisprimary DESC, count(*), i.indexrelid
+		LIMIT 1
+	) key ON true
+	WHERE r.oid = to_regclass(format('%I.%I', $1::text, $2::text))`
+
+// keyColumns returns the columns identifying a single row of the table:
+// those of its primary key or, without one, of the smallest unique
+// constraint (or index) whose columns cannot be NULL. Without either, rows
+// are identified by ctid. Relations without a ctid, such as views, have no
+// row key and their rows are read-only.
+func keyColumns(ctx context.Context, conn querier, schema string, table string) ([]string, error) {
+	rows, err := conn.Query(ctx, rowKeyQuery, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[[]string])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrTableNotFound
+	}
+
+	return columns, err
+}
+
+// keyCondition builds the condition matching the row identified by key,
+// which must hold a value for every key column of the table and nothing
+// else. Values are added to b as parameters. Relations without a row key
+// fail with models.ErrReadOnlyRelation.
+func keyCondition(ctx context.Context, conn *pgx.Conn, schema string, table string, key map[string]any, b *filterBuilder) (string, error) {
+	columns, err := keyColumns(ctx, conn, schema, table)
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", models.ErrReadOnlyRelation
+	}
+
+	if len(key) != len(columns) || slices.ContainsFunc(columns, func(c string) bool { _, ok := key[c]; return !ok }) {
+		return "", fmt.Errorf("%w: the key must hold exactly the columns %s", models.ErrInvalidKey, strings.Join(columns, ", "))
+	}
+
+	conds := make([]string, len(columns))
+	for i, col := range columns {
+		if key[col] == nil {
+			return "", fmt.Errorf("%w: %q cannot be null", models.ErrInvalidKey, col)
+		}
+		conds[i] = fmt.Sprintf("%s = %s", pgx.Identifier{col}.Sanitize(), b.param(key[col]))
+	}
+
+	return strings.Join(conds, " AND "), nil
+}
+
+// versionedRow builds the condition matching the row identified by key as
+// long as it is still at version.
+func versionedRow(ctx context.Context, conn *pgx.Conn, schema string, table string, key map[string]any, version string, b *filterBuilder) (string, error) {
+	cond, err := keyCondition(ctx, conn, schema, table, key, b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s AND %s = %s", cond, versionColumn, b.param(version)), nil
+}
+
+// singleRow checks that a statement on the row identified by key and
+// version affected exactly one. When it affected none, the row was either
+// deleted or changed since that version was read: the latter is reported
+// with the current contents of the row. Callers roll back on error.
+func singleRow(ctx context.Context, conn *pgx.Conn, schema string, table string, key map[string]any, rowsAffected int64) error {
+	switch {
+	case rowsAffected == 1:
+		return nil
+	case rowsAffected > 1:
+		return models.ErrAmbiguousRow
+	}
+
+	b := &filterBuilder{}
+	cond, err := keyCondition(ctx, conn, schema, table, key, b)
+	if err != nil {
+		return err
+	}
+
+	// The key holds exactly the key columns, as keyCondition checked.
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, rowList(slices.Collect(maps.Keys(key))), pgx.Identifier{schema, table}.Sanitize(), cond)
+
+	sink := &rowMaps{}
+	if err := streamStatement(ctx, conn, query, b.params, nil, 2, &models.QueryResult{}, sink); err != nil {
+		return err
+	}
+
+	switch len(sink.rows) {
+	case 0:
+		return models.ErrRowNotFound
+	case 1:
+		return &models.RowConflictError{Row: sink.rows[0]}
+	default:
+		return models.ErrAmbiguousRow
+	}
+}
+
+// rowList is the select list of the rows of a relation with the row key
+// rowKey (see keyColumns): every column with the version of the row and,
+// when it is keyed by ctid, its ctid. Relations without a row key have
+// neither, and their rows cannot be changed.
+func rowList(rowKey []string) string {
+	switch {
+	case len(rowKey) == 0:
+		return "*"
+	case slices.Equal(rowKey, []string{ctidColumn}):
+		return fmt.Sprintf("%[1]s::text AS %[1]s, %[2]s::text AS %[2]s, *", versionColumn, ctidColumn)
+	default:
+		return fmt.Sprintf("%[1]s::text AS %[1]s, *", versionColumn)
+	}
+}
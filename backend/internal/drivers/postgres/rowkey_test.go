@@ -0,0 +1,24 @@
+package postgres
+
+import "testing"
+
+func TestRowList(t *testing.T) {
+	tests := []struct {
+		name   string
+		rowKey []string
+		want   string
+	}{
+		{name: "keyed by columns", rowKey: []string{"org", "id"}, want: `xmin::text AS xmin, *`},
+		{name: "keyed by ctid", rowKey: []string{"ctid"}, want: `xmin::text AS xmin, ctid::text AS ctid, *`},
+		{name: "view", rowKey: []string{}, want: `*`},
+		{name: "missing row key", rowKey: nil, want: `*`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rowList(tt.rowKey); got != tt.want {
+				t.Errorf("rowList() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,81 +3,404 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func GetRows(db *pgxpool.Pool, schema string, table string, page int, limit int) (*models.ApiResponse, error) {
-	rowsList := make([]map[string]any, 0, limit)
+// GetRows reads a page of the table, keeping only the rows matching
+// q.Filter, in the order of q.Sort, and counts the matching rows as asked
+// by q.Count. A page larger than limits.MaxRows is cut short and the
+// response marked truncated.
+//
+// With keyset paging the page starts right after (or ends right before) the
+// row of q.Cursor instead of skipping rows, so reading deep into a large
+// table costs no more than reading its first page.
+func GetRows(ctx context.Context, db *pgxpool.Pool, schema string, table string, q models.RowsQuery, limits models.QueryLimits) (*models.ApiResponse, error) {
+	sel, err := selectRows(ctx, db, schema, table, q)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	capped := limits.MaxRows > 0 && limit > limits.MaxRows
+	if capped {
+		limit = limits.MaxRows
+	}
+
+	var (
+		where  = whereClause(sel.filter)
+		params = sel.params
+		offset = (q.Page - 1) * q.Limit
+		cursor *rowsCursor
+	)
+	if q.Paging == models.PagingKeyset {
+		if !sel.keyed {
+			return nil, fmt.Errorf("%w: keyset paging needs a table with a primary or unique key", models.ErrInvalidCursor)
+		}
+		offset = 0
 
-	offsetValue := (page - 1) * limit
-	query := fmt.Sprintf(`SELECT * FROM "%s"."%s" LIMIT %d OFFSET %d`, schema, table, limit, offsetValue)
+		if q.Cursor != "" {
+			cursor, err = decodeCursor(q.Cursor, sel.terms)
+			if err != nil {
+				return nil, err
+			}
 
-	rows, err := db.Query(context.Background(), query)
-	cols := rows.FieldDescriptions()
+			b := &filterBuilder{params: slices.Clone(sel.params)}
+			where = whereClause(sel.filter, keysetCondition(sel.terms, cursor, b))
+			params = b.params
+		}
+	}
+
+	// A page ending before the cursor is read backwards from it.
+	backwards := cursor != nil && cursor.Before
+
+	// One extra row tells whether more rows follow the page. Rows of tables
+	// without a row key can only be changed by ctid; rows of views and other
+	// relations without versions cannot be changed.
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s%s%s LIMIT %d OFFSET %d`,
+		rowList(sel.rowKey), pgx.Identifier{schema, table}.Sanitize(), where, orderClause(sel.terms, backwards), limit+1, offset,
+	)
+
+	conn, release, err := acquire(ctx, db)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer release()
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), true, limits.TimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sink := &rowMaps{rows: make([]map[string]any, 0, limit)}
+	result := &models.QueryResult{}
+	if err := streamStatement(ctx, tx.Conn(), query, params, nil, limit, result, sink); err != nil {
+		return nil, err
+	}
+
+	rows := sink.rows
+	if backwards {
+		slices.Reverse(rows)
+	}
+
+	pagination := &models.Pagination{
+		Page:    q.Page,
+		Limit:   q.Limit,
+		HasNext: result.Truncated,
+	}
+
+	if q.Paging == models.PagingKeyset {
+		pagination.Page = 0
+		if err := setCursors(pagination, sel.terms, rows, cursor, result.Truncated); err != nil {
+			return nil, err
+		}
+	}
+
+	switch q.Count {
+	case models.CountExact:
+		total, err := countRows(ctx, tx.Conn(), schema, table, whereClause(sel.filter), sel.params)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+		if q.Paging != models.PagingKeyset {
+			pagination.HasNext = int64(q.Page*q.Limit) < total
+		}
+
+	case models.CountEstimate:
+		total, err := estimateRows(ctx, tx.Conn(), schema, table, whereClause(sel.filter), sel.params)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+		pagination.TotalEstimated = true
+	}
+
+	return &models.ApiResponse{
+		Status:     http.StatusOK,
+		Message:    "success",
+		Data:       rows,
+		Truncated:  capped && result.Truncated,
+		Pagination: pagination,
+	}, nil
+}
 
-	for rows.Next() {
-		values, _ := rows.Values()
-		rowMap := map[string]any{}
+// setCursors fills in the cursors of the pages around rows, read from
+// cursor (nil for the first page). more reports whether rows were left out
+// past the end of the page in the direction it was read.
+func setCursors(p *models.Pagination, terms []orderTerm, rows []map[string]any, cursor *rowsCursor, more bool) error {
+	if len(rows) == 0 {
+		return nil
+	}
 
-		for i, col := range cols {
-			rowMap[col.Name] = values[i]
+	var err error
+	first, last := rows[0], rows[len(rows)-1]
+	backwards := cursor != nil && cursor.Before
+
+	if more || backwards {
+		if p.NextCursor, err = encodeCursor(terms, last, false); err != nil {
+			return err
+		}
+	}
+	if (more && backwards) || (cursor != nil && !backwards) {
+		if p.PrevCursor, err = encodeCursor(terms, first, true); err != nil {
+			return err
 		}
+	}
 
-		rowsList = append(rowsList, rowMap)
+	p.HasNext = p.NextCursor != ""
+	return nil
+}
+
+// StreamRows reads a page of the table (or all of it when q.Limit is 0),
+// keeping only the rows matching q.Filter in the order of q.Sort, and hands
+// each row to sink as it arrives, stopping after limits.MaxRows rows.
+func StreamRows(ctx context.Context, db *pgxpool.Pool, schema string, table string, q models.RowsQuery, limits models.QueryLimits, sink models.RowSink) (*models.ApiResponse, error) {
+	sel, err := selectRows(ctx, db, schema, table, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows carry their version, and ctid without a row key, as with GetRows,
+	// so streamed rows can be edited too (except those of views and other
+	// relations without versions).
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s%s%s`,
+		rowList(sel.rowKey), pgx.Identifier{schema, table}.Sanitize(), whereClause(sel.filter), orderClause(sel.terms, false),
+	)
+	if q.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d OFFSET %d`, q.Limit, (max(q.Page, 1)-1)*q.Limit)
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), true, limits.TimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result := &models.QueryResult{}
+	if err := streamStatement(ctx, tx.Conn(), query, sel.params, nil, limits.MaxRows, result, sink); err != nil {
+		return nil, err
+	}
+
+	return &models.ApiResponse{
+		Status:    http.StatusOK,
+		Message:   "success",
+		Data:      result,
+		Truncated: result.Truncated,
+	}, nil
+}
+
+// setClause builds the assignments of an UPDATE from row, checking that
+// every column belongs to the table. Values are added to b as parameters,
+// whose types the server infers from the columns they are assigned to.
+func setClause(columns []models.ColumnModel, row map[string]any, b *filterBuilder) (string, error) {
+	if len(row) == 0 {
+		return "", fmt.Errorf("%w: no column to update", models.ErrInvalidRow)
+	}
+
+	names := slices.Sorted(maps.Keys(row))
+	assignments := make([]string, len(names))
+	for i, name := range names {
+		if !slices.ContainsFunc(columns, func(c models.ColumnModel) bool { return c.Name == name }) {
+			return "", fmt.Errorf("%w: unknown column %q", models.ErrInvalidRow, name)
+		}
+		assignments[i] = fmt.Sprintf("%s = %s", pgx.Identifier{name}.Sanitize(), b.param(row[name]))
+	}
+
+	return strings.Join(assignments, ", "), nil
+}
+
+// rowsSelection holds what selects and orders the rows of a RowsQuery,
+// checked against the columns of the table.
+type rowsSelection struct {
+	// filter is the condition of the filter, empty without one, and params
+	// its parameters.
+	filter string
+	params []models.QueryParam
+
+	// terms order the rows; keyed reports whether they include the whole
+	// row key, making the order unique.
+	terms []orderTerm
+	keyed bool
+
+	// rowKey identifies the rows, as keyColumns returns it.
+	rowKey []string
+}
+
+func selectRows(ctx context.Context, db *pgxpool.Pool, schema string, table string, q models.RowsQuery) (*rowsSelection, error) {
+	info, err := tableColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := &rowsSelection{
+		keyed:  hasRowKey(info.columns),
+		rowKey: info.rowKey,
+	}
+
+	if q.Filter != nil {
+		b := newFilterBuilder(info.columns)
+		if sel.filter, err = b.compile(*q.Filter); err != nil {
+			return nil, err
+		}
+		sel.params = b.params
+	}
+
+	if sel.terms, err = orderTerms(info.columns, q.Sort); err != nil {
+		return nil, err
+	}
+
+	return sel, nil
+}
+
+// whereClause joins the non-empty conditions into a WHERE clause, empty
+// when there are none.
+func whereClause(conds ...string) string {
+	conds = slices.DeleteFunc(conds, func(c string) bool { return c == "" })
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// rowMaps is a RowSink keeping every row as a map from column name to
+// value.
+type rowMaps struct {
+	columns []models.QueryColumn
+	rows    []map[string]any
+}
+
+func (m *rowMaps) Columns(columns []models.QueryColumn) error {
+	m.columns = columns
+	return nil
+}
+
+func (m *rowMaps) Row(values []any) error {
+	row := make(map[string]any, len(values))
+	for i, col := range m.columns {
+		row[col.Name] = values[i]
+	}
+
+	m.rows = append(m.rows, row)
+	return nil
+}
+
+// InsertRow inserts row, a map from column name to value, into the table,
+// and returns the row as stored. Values are converted to the type of their
+// column as in UpdateRow. A row skipped on conflict is returned as null.
+func InsertRow(ctx context.Context, db *pgxpool.Pool, schema string, table string, row map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	info, err := tableColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	written, err := insertRow(ctx, tx.Conn(), schema, table, info, row, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
 
 	return &models.ApiResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    rowsList,
+		Data:    written,
 	}, nil
 }
 
-func InsertRow(db *pgxpool.Pool, schema string, table string, row map[string]any) (*models.ApiResponse, error) {
-	columns := make([]string, 0, len(row))
-	values := make([]any, 0, len(row))
-	placeholders := make([]string, 0, len(row))
+// InsertRows inserts rows into the table in a single statement, so either
+// all of them are stored or none, and returns them as stored. A column
+// missing from some rows gets its default in those, except with do_update
+// where every row must hold the same columns.
+func InsertRows(ctx context.Context, db *pgxpool.Pool, schema string, table string, rows []map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	info, err := tableColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
 
-	i := 1
-	for col, val := range row {
-		columns = append(columns, fmt.Sprintf(`"%s"`, col))
-		values = append(values, val)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-		i++
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	query := fmt.Sprintf(`
-		INSERT INTO "%s"."%s" (%s)
-		VALUES (%s)
-	`, schema, table, strings.Join(columns, ","), strings.Join(placeholders, ","))
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	_, err := db.Exec(context.Background(), query, values...)
+	written, err := insertRows(ctx, tx.Conn(), schema, table, info, rows, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return &models.ApiResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    row,
+		Data:    written,
 	}, nil
 }
 
-func DeleteRow(db *pgxpool.Pool, schema string, table string, pkColumn string, pkValue any) (*models.ApiResponse, error) {
-	query := fmt.Sprintf(`DELETE FROM "%s"."%s" WHERE "%s" = $1`, schema, table, pkColumn)
+// DeleteRow deletes the row identified by key, the values of the key
+// columns of the table (see keyColumns), at version, as read by GetRows.
+// Nothing is deleted unless the key matches exactly one row and the row is
+// still at that version. Views and other relations whose rows have no
+// version fail with models.ErrReadOnlyRelation.
+func DeleteRow(ctx context.Context, db *pgxpool.Pool, schema string, table string, key map[string]any, version string, timeoutMs int) (*models.ApiResponse, error) {
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	_, err := db.Exec(context.Background(), query, pkValue)
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, timeoutMs)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	if _, err := deleteRow(ctx, tx.Conn(), schema, table, key, version); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
 	return &models.ApiResponse{
 		Status:  http.StatusOK,
@@ -85,32 +408,216 @@ func DeleteRow(db *pgxpool.Pool, schema string, table string, pkColumn string, p
 	}, nil
 }
 
-func UpdateRow(db *pgxpool.Pool, schema string, table string, pkColumn string, pkValue any, row map[string]any) (*models.ApiResponse, error) {
-	colsAndVals := make([]string, 0, len(row))
+// UpdateRow updates the row identified by key, the values of the key
+// columns of the table (see keyColumns), at version, as read by GetRows.
+// Nothing is updated unless the key matches exactly one row and the row is
+// still at that version. The row is returned as stored, with its new
+// version. Views and other relations whose rows have no version fail with
+// models.ErrReadOnlyRelation.
+//
+// Values are bound as parameters and converted to the type of their column
+// as encodeParam does: objects and arrays become json, jsonb or array
+// values, \x hex strings (as rows are read) or {"base64": ...} objects
+// bytea, and null sets NULL.
+func UpdateRow(ctx context.Context, db *pgxpool.Pool, schema string, table string, key map[string]any, version string, row map[string]any, timeoutMs int) (*models.ApiResponse, error) {
+	info, err := tableColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
 
-	for col, val := range row {
-		switch val.(type) {
-		case string:
-			colsAndVals = append(colsAndVals, fmt.Sprintf(`%s = '%s'`, col, val))
-		default:
-			colsAndVals = append(colsAndVals, fmt.Sprintf(`%s = %v`, col, val))
-		}
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	query := fmt.Sprintf(`
-		UPDATE "%s"."%s"
-		SET %s
-		WHERE %s = %v
-	`, schema, table, strings.Join(colsAndVals, ","), pkColumn, pkValue)
+	tx, err := beginWithTimeout(ctx, conn.Conn(), false, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	_, err := db.Exec(context.Background(), query)
+	written, err := updateRow(ctx, tx.Conn(), schema, table, info, key, version, row)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return &models.ApiResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    row,
+		Data:    written,
 	}, nil
 }
+
+// tableColumns returns the columns and row key of the table, failing with
+// models.ErrTableNotFound when it does not exist.
+func tableColumns(ctx context.Context, db *pgxpool.Pool, schema string, table string) (*tableInfo, error) {
+	info, err := readColumns(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.columns) == 0 {
+		return nil, fmt.Errorf("%w: %s", models.ErrTableNotFound, pgx.Identifier{schema, table}.Sanitize())
+	}
+
+	return info, nil
+}
+
+// maxParams is the most parameters a statement can have.
+const maxParams = 65535
+
+// insertRow inserts row on conn. Without any value the row gets the
+// defaults of every column.
+func insertRow(ctx context.Context, conn *pgx.Conn, schema string, table string, info *tableInfo, row map[string]any, opts models.InsertOptions) (*models.RowWrite, error) {
+	written, err := insertRows(ctx, conn, schema, table, info, []map[string]any{row}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return singleWrite(written), nil
+}
+
+// insertRows inserts rows on conn in a single statement.
+func insertRows(ctx context.Context, conn *pgx.Conn, schema string, table string, info *tableInfo, rows []map[string]any, opts models.InsertOptions) (*models.RowsWrite, error) {
+	names := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row {
+			names[name] = true
+		}
+	}
+	sorted := slices.Sorted(maps.Keys(names))
+
+	cols := make([]string, len(sorted))
+	for i, name := range sorted {
+		if !slices.ContainsFunc(info.columns, func(c models.ColumnModel) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("%w: unknown column %q", models.ErrInvalidRow, name)
+		}
+		cols[i] = pgx.Identifier{name}.Sanitize()
+	}
+
+	if len(sorted) == 0 && len(rows) > 1 {
+		return nil, fmt.Errorf("%w: rows without values can only be inserted one at a time", models.ErrInvalidRow)
+	}
+
+	// A column missing from a row gets its default, which do_update would
+	// then write over the stored value.
+	if opts.OnConflict == models.OnConflictDoUpdate {
+		for i, row := range rows {
+			if len(row) != len(sorted) {
+				return nil, fmt.Errorf("%w: with do_update every row must hold the same columns, row %d does not", models.ErrInvalidRow, i)
+			}
+		}
+	}
+	if len(sorted)*len(rows) > maxParams {
+		return nil, fmt.Errorf("%w: too many values in one insert, at most %d", models.ErrInvalidRow, maxParams)
+	}
+
+	conflict, err := conflictClause(info.columns, sorted, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &filterBuilder{}
+	tuples := make([]string, len(rows))
+	for i, row := range rows {
+		values := make([]string, len(sorted))
+		for j, name := range sorted {
+			if value, ok := row[name]; ok {
+				values[j] = b.param(value)
+			} else {
+				values[j] = "DEFAULT"
+			}
+		}
+		tuples[i] = "(" + strings.Join(values, ", ") + ")"
+	}
+
+	name := pgx.Identifier{schema, table}.Sanitize()
+
+	query := fmt.Sprintf(`INSERT INTO %s DEFAULT VALUES`, name)
+	if len(sorted) > 0 {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (%s)
+			VALUES %s
+		`, name, strings.Join(cols, ", "), strings.Join(tuples, ", "))
+	}
+
+	return returningRows(ctx, conn, query+conflict+" RETURNING "+rowList(info.rowKey), b.params)
+}
+
+// updateRow updates the row identified by key and version on conn, failing
+// unless they match exactly one row. Callers roll back on failure.
+func updateRow(ctx context.Context, conn *pgx.Conn, schema string, table string, info *tableInfo, key map[string]any, version string, row map[string]any) (*models.RowWrite, error) {
+	b := &filterBuilder{}
+	set, err := setClause(info.columns, row, b)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := versionedRow(ctx, conn, schema, table, key, version, b)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET %s
+		WHERE %s
+		RETURNING %s
+	`, pgx.Identifier{schema, table}.Sanitize(), set, cond, rowList(info.rowKey))
+
+	written, err := returningRows(ctx, conn, query, b.params)
+	if err != nil {
+		return nil, err
+	}
+
+	return singleWrite(written), singleRow(ctx, conn, schema, table, key, written.RowsAffected)
+}
+
+// deleteRow deletes the row identified by key and version on conn, failing
+// unless they match exactly one row. Callers roll back on failure.
+func deleteRow(ctx context.Context, conn *pgx.Conn, schema string, table string, key map[string]any, version string) (*models.QueryResult, error) {
+	b := &filterBuilder{}
+	cond, err := versionedRow(ctx, conn, schema, table, key, version, b)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s`, pgx.Identifier{schema, table}.Sanitize(), cond)
+
+	qr, err := runStatement(ctx, conn, query, b.params, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return qr, singleRow(ctx, conn, schema, table, key, qr.RowsAffected)
+}
+
+// returningRows runs an INSERT or UPDATE ending in a RETURNING clause and
+// reads the rows back as GetRows would.
+func returningRows(ctx context.Context, conn *pgx.Conn, query string, params []models.QueryParam) (*models.RowsWrite, error) {
+	sink := &rowMaps{rows: []map[string]any{}}
+	result := &models.QueryResult{}
+	if err := streamStatement(ctx, conn, query, params, nil, 0, result, sink); err != nil {
+		return nil, err
+	}
+
+	return &models.RowsWrite{Rows: sink.rows, RowsAffected: result.RowsAffected}, nil
+}
+
+// singleWrite is the result of a write of a single row, which has no row
+// when it was skipped.
+func singleWrite(written *models.RowsWrite) *models.RowWrite {
+	single := &models.RowWrite{RowsAffected: written.RowsAffected}
+	if len(written.Rows) == 1 {
+		single.Row = written.Rows[0]
+	}
+	return single
+}
+
+func hasRowKey(columns []models.ColumnModel) bool {
+	return slices.ContainsFunc(columns, func(c models.ColumnModel) bool { return c.IsRowKey })
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func GetSchemas(db *pgxpool.Pool) (*models.ApiResponse, error) {
+func GetSchemas(ctx context.Context, db *pgxpool.Pool) (*models.ApiResponse, error) {
 	var schemasList []string
 
 	query := `
@@ -18,7 +18,7 @@ func GetSchemas(db *pgxpool.Pool) (*models.ApiResponse, error) {
 	`
 
 	rows, err := db.Query(
-		context.Background(),
+		ctx,
 		query,
 	)
 	if err != nil {
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/notices"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RunSessionQuery runs a script on the connection of a session. Unlike
+// RunQuery nothing is wrapped in a transaction: the script may BEGIN, COMMIT
+// or ROLLBACK itself, and a transaction left open carries over to the next
+// query of the session. In transaction mode the statements following a
+// failure are skipped; in continue mode they still run.
+func RunSessionQuery(ctx context.Context, conn *pgxpool.Conn, req models.QueryRequest) (*models.ApiResponse, error) {
+	defer activity.SetPID(ctx, conn.Conn().PgConn().PID())()
+
+	if err := setSessionTimeout(ctx, conn, req.TimeoutMs); err != nil {
+		return nil, err
+	}
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := SplitStatements(req.Query)
+	offsets := statementOffsets(req.Query, statements)
+	result := &models.ScriptResult{
+		Mode:       scriptMode(req.Mode, statements),
+		Statements: make([]models.StatementResult, len(statements)),
+	}
+
+	collector := notices.Listen(conn.Conn().PgConn())
+	defer collector.Stop()
+
+	for i, stmt := range statements {
+		res := &result.Statements[i]
+		res.Statement = stmt
+
+		if result.Failed > 0 && result.Mode != models.QueryModeContinue {
+			res.Skipped = true
+			continue
+		}
+
+		collector.Take()
+
+		res.StartedAt = time.Now()
+		qr, err := runStatement(ctx, conn.Conn(), stmt, req.Params, hints, req.MaxRows)
+		res.DurationMs = durationMs(time.Since(res.StartedAt))
+		res.Notices = collector.Take()
+
+		if err != nil {
+			failStatement(res, err, offsets[i])
+			result.Failed++
+			continue
+		}
+
+		res.QueryResult = qr
+	}
+
+	result.Transaction = sessions.Transaction(conn)
+
+	message := "success"
+	if result.Failed > 0 {
+		message = "completed with errors"
+	}
+
+	return &models.ApiResponse{
+		Status:    http.StatusOK,
+		Message:   message,
+		Data:      result,
+		Truncated: truncated(result),
+	}, nil
+}
+
+// StreamSessionQuery runs a single statement on the connection of a session,
+// handing rows to sink as they arrive.
+func StreamSessionQuery(ctx context.Context, conn *pgxpool.Conn, req models.QueryRequest, sink models.RowSink) (*models.ApiResponse, error) {
+	statements := SplitStatements(req.Query)
+	if len(statements) != 1 {
+		return nil, errors.New("streaming expects exactly one statement")
+	}
+
+	defer activity.SetPID(ctx, conn.Conn().PgConn().PID())()
+
+	if err := setSessionTimeout(ctx, conn, req.TimeoutMs); err != nil {
+		return nil, err
+	}
+
+	hints, err := paramTypeOIDs(ctx, conn.Conn(), req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.QueryResult{}
+	if err := streamStatement(ctx, conn.Conn(), statements[0], req.Params, hints, req.MaxRows, result, sink); err != nil {
+		pgerror.ShiftPosition(err, statementOffsets(req.Query, statements)[0])
+		return nil, err
+	}
+
+	return &models.ApiResponse{
+		Status:    http.StatusOK,
+		Message:   "success",
+		Data:      result,
+		Truncated: result.Truncated,
+	}, nil
+}
+
+// setSessionTimeout sets the statement timeout of a session connection. It
+// is never reset since the connection is closed when the session ends. In a
+// failed transaction nothing but ROLLBACK runs, so it is left alone.
+func setSessionTimeout(ctx context.Context, conn *pgxpool.Conn, timeoutMs int) error {
+	if sessions.Transaction(conn) == models.SessionFailed {
+		return nil
+	}
+
+	_, err := conn.Exec(ctx, `SELECT set_config('statement_timeout', $1, false)`, strconv.Itoa(timeoutMs))
+	return err
+}
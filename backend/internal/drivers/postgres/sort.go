@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// orderTerm is a column of an ORDER BY clause. NullsFirst is always set
+// explicitly, so the order can be reversed exactly.
+type orderTerm struct {
+	Column     string
+	Desc       bool
+	NullsFirst bool
+	Nullable   bool
+}
+
+// orderTerms turns sorts into ORDER BY terms, followed by the row key
+// columns not sorted on yet so the order is deterministic.
+func orderTerms(columns []models.ColumnModel, sorts []models.RowSort) ([]orderTerm, error) {
+	var terms []orderTerm
+
+	sorted := func(column string) bool {
+		return slices.ContainsFunc(terms, func(t orderTerm) bool { return t.Column == column })
+	}
+
+	for _, s := range sorts {
+		i := slices.IndexFunc(columns, func(c models.ColumnModel) bool { return c.Name == s.Column })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", models.ErrInvalidSort, s.Column)
+		}
+		if sorted(s.Column) {
+			return nil, fmt.Errorf("%w: column %q is sorted on twice", models.ErrInvalidSort, s.Column)
+		}
+
+		// Postgres puts NULLs last when ascending, first when descending.
+		term := orderTerm{Column: s.Column, Desc: s.Desc, NullsFirst: s.Desc, Nullable: nullable(columns[i])}
+		switch s.Nulls {
+		case "":
+		case models.NullsFirst:
+			term.NullsFirst = true
+		case models.NullsLast:
+			term.NullsFirst = false
+		default:
+			return nil, fmt.Errorf("%w: nulls must be first or last", models.ErrInvalidSort)
+		}
+
+		terms = append(terms, term)
+	}
+
+	for _, c := range columns {
+		if c.IsRowKey && !sorted(c.Name) {
+			terms = append(terms, orderTerm{Column: c.Name})
+		}
+	}
+
+	return terms, nil
+}
+
+// orderClause builds the ORDER BY clause for terms, or for the opposite
+// order when reverse is set. It is empty when there are no terms.
+func orderClause(terms []orderTerm, reverse bool) string {
+	if len(terms) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		desc, nullsFirst := t.Desc != reverse, t.NullsFirst != reverse
+
+		parts[i] = pgx.Identifier{t.Column}.Sanitize()
+		if desc {
+			parts[i] += " DESC"
+		}
+		if nullsFirst != desc {
+			// Only spelled out when it differs from the default.
+			if nullsFirst {
+				parts[i] += " NULLS FIRST"
+			} else {
+				parts[i] += " NULLS LAST"
+			}
+		}
+	}
+
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+func nullable(c models.ColumnModel) bool {
+	return c.IsNullable == nil || *c.IsNullable != "NO"
+}
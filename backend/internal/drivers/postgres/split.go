@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitStatements splits a script into its individual statements on
+// top-level semicolons. Quoted strings, quoted identifiers, dollar-quoted
+// bodies and comments are kept intact, and statements made only of
+// whitespace or comments are dropped.
+func SplitStatements(script string) []string {
+	var (
+		statements []string
+		start      int
+		content    bool
+	)
+
+	flush := func(end int) {
+		if content {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start = end + 1
+		content = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+
+		switch {
+		case c == ';':
+			flush(i)
+
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			depth := 0
+			for ; i < len(script); i++ {
+				if script[i] == '/' && i+1 < len(script) && script[i+1] == '*' {
+					depth++
+					i++
+				} else if script[i] == '*' && i+1 < len(script) && script[i+1] == '/' {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				}
+			}
+
+		case c == '\'' || c == '"':
+			content = true
+			escapes := c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') &&
+				(i < 2 || !isIdentChar(script[i-2]))
+			for i++; i < len(script); i++ {
+				if escapes && script[i] == '\\' {
+					i++
+					continue
+				}
+				if script[i] == c {
+					if i+1 < len(script) && script[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+
+		case c == '$':
+			content = true
+			if i > 0 && isIdentChar(script[i-1]) {
+				continue
+			}
+			tag, ok := dollarTag(script[i:])
+			if !ok {
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += len(tag) + end + len(tag) - 1
+			}
+
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			content = true
+		}
+	}
+
+	flush(len(script))
+
+	return statements
+}
+
+// statementOffsets returns the character offset in script at which each of
+// the statements returned by SplitStatements starts.
+func statementOffsets(script string, statements []string) []int {
+	offsets := make([]int, len(statements))
+
+	start := 0
+	for i, stmt := range statements {
+		if idx := strings.Index(script[start:], stmt); idx >= 0 {
+			start += idx
+		}
+		offsets[i] = utf8.RuneCountInString(script[:start])
+		start += len(stmt)
+	}
+
+	return offsets
+}
+
+// dollarTag returns the opening tag ($$ or $name$) at the start of s.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1], true
+		case isIdentChar(s[i]) && !(i == 1 && s[i] >= '0' && s[i] <= '9'):
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// FirstKeyword returns the first word of a statement in lower case, skipping
+// leading whitespace, comments and parentheses.
+func FirstKeyword(stmt string) string {
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+
+		switch {
+		case c == '-' && i+1 < len(stmt) && stmt[i+1] == '-':
+			for i < len(stmt) && stmt[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(stmt) && stmt[i+1] == '*':
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			i += end + 3
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(':
+
+		default:
+			j := i
+			for j < len(stmt) && isIdentChar(stmt[j]) {
+				j++
+			}
+			return strings.ToLower(stmt[i:j])
+		}
+	}
+
+	return ""
+}
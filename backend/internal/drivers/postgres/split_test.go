@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "single statement without semicolon",
+			script: "SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "several statements",
+			script: "SELECT 1; SELECT 2;\nSELECT 3",
+			want:   []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name:   "empty statements dropped",
+			script: " ; ;\n\tSELECT 1;;",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "empty script",
+			script: "  \n ",
+			want:   nil,
+		},
+		{
+			name:   "semicolon in string",
+			script: "SELECT 'a;b'; SELECT 2",
+			want:   []string{"SELECT 'a;b'", "SELECT 2"},
+		},
+		{
+			name:   "doubled quote in string",
+			script: "SELECT 'it''s;'; SELECT 2",
+			want:   []string{"SELECT 'it''s;'", "SELECT 2"},
+		},
+		{
+			name:   "semicolon in quoted identifier",
+			script: `SELECT 1 AS "a;b"; SELECT 2`,
+			want:   []string{`SELECT 1 AS "a;b"`, "SELECT 2"},
+		},
+		{
+			name:   "escaped quote in E string",
+			script: `SELECT E'a\';b'; SELECT 2`,
+			want:   []string{`SELECT E'a\';b'`, "SELECT 2"},
+		},
+		{
+			name:   "lower case e string",
+			script: `SELECT e'\\'; SELECT 2`,
+			want:   []string{`SELECT e'\\'`, "SELECT 2"},
+		},
+		{
+			name:   "backslash in standard string",
+			script: `SELECT 'a\'; SELECT 2`,
+			want:   []string{`SELECT 'a\'`, "SELECT 2"},
+		},
+		{
+			name:   "identifier ending in e before string",
+			script: `SELECT name'\'; SELECT 2`,
+			want:   []string{`SELECT name'\'`, "SELECT 2"},
+		},
+		{
+			name:   "dollar quoted body",
+			script: "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT 2",
+			want:   []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT 2"},
+		},
+		{
+			name:   "tagged dollar quote holding $$",
+			script: "DO $body$ BEGIN PERFORM '$$;'; END $body$; SELECT 2",
+			want:   []string{"DO $body$ BEGIN PERFORM '$$;'; END $body$", "SELECT 2"},
+		},
+		{
+			name:   "positional parameter is not a dollar quote",
+			script: "SELECT $1; SELECT $2",
+			want:   []string{"SELECT $1", "SELECT $2"},
+		},
+		{
+			name:   "dollar sign inside identifier",
+			script: "SELECT a$b$c FROM t; SELECT 2",
+			want:   []string{"SELECT a$b$c FROM t", "SELECT 2"},
+		},
+		{
+			name:   "unterminated dollar quote",
+			script: "SELECT $$ a; b",
+			want:   []string{"SELECT $$ a; b"},
+		},
+		{
+			name:   "line comment",
+			script: "SELECT 1 -- a; b\n; SELECT 2",
+			want:   []string{"SELECT 1 -- a; b", "SELECT 2"},
+		},
+		{
+			name:   "nested block comment",
+			script: "SELECT /* a /* b; */ c; */ 1; SELECT 2",
+			want:   []string{"SELECT /* a /* b; */ c; */ 1", "SELECT 2"},
+		},
+		{
+			name:   "comment only statements dropped",
+			script: "-- nothing;\n/* here; */; SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitStatements(tt.script)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementOffsets(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []int
+	}{
+		{
+			name:   "single statement",
+			script: "  SELECT 1",
+			want:   []int{2},
+		},
+		{
+			name:   "several statements",
+			script: "SELECT 1;\n  SELECT 2; SELECT 3",
+			want:   []int{0, 12, 22},
+		},
+		{
+			name:   "repeated statement",
+			script: "SELECT 1; SELECT 1",
+			want:   []int{0, 10},
+		},
+		{
+			name:   "offsets count characters",
+			script: "SELECT 'é'; SELECT 2",
+			want:   []int{0, 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statementOffsets(tt.script, SplitStatements(tt.script))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("statementOffsets(%q) = %v, want %v", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstKeyword(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{stmt: "SELECT 1", want: "select"},
+		{stmt: "  \n\tVacuum t", want: "vacuum"},
+		{stmt: "-- note\nINSERT INTO t VALUES (1)", want: "insert"},
+		{stmt: "/* note */ (SELECT 1)", want: "select"},
+		{stmt: "/* unterminated", want: ""},
+		{stmt: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := FirstKeyword(tt.stmt); got != tt.want {
+			t.Errorf("FirstKeyword(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
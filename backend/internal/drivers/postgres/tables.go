@@ -8,7 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func GetTables(db *pgxpool.Pool, schema string) (*models.ApiResponse, error) {
+func GetTables(ctx context.Context, db *pgxpool.Pool, schema string) (*models.ApiResponse, error) {
 	query := `
 		SELECT table_name
 		FROM information_schema.tables
@@ -17,7 +17,7 @@ func GetTables(db *pgxpool.Pool, schema string) (*models.ApiResponse, error) {
 	`
 
 	rows, err := db.Query(
-		context.Background(),
+		ctx,
 		query,
 		schema,
 	)
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// resetTimeout bounds how long resetting the statement timeout of a
+// connection may take before the connection is dropped instead.
+const resetTimeout = 5 * time.Second
+
+// beginWithTimeout starts a transaction, read-only if asked, in which the
+// server cancels any statement running longer than timeoutMs.
+func beginWithTimeout(ctx context.Context, conn *pgx.Conn, readOnly bool, timeoutMs int) (pgx.Tx, error) {
+	var tx pgx.Tx
+	var err error
+	if readOnly {
+		tx, err = beginReadOnly(ctx, conn)
+	} else {
+		tx, err = conn.Begin(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if timeoutMs > 0 {
+		if _, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`, strconv.Itoa(timeoutMs)); err != nil {
+			tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// setStatementTimeout applies timeoutMs to the whole session, for scripts
+// whose statements run outside of a transaction. The returned function puts
+// the server default back; if that fails the connection is closed so the
+// pool doesn't hand it out again.
+func setStatementTimeout(ctx context.Context, conn *pgxpool.Conn, timeoutMs int) (func(), error) {
+	if timeoutMs <= 0 {
+		return func() {}, nil
+	}
+
+	if _, err := conn.Exec(ctx, `SELECT set_config('statement_timeout', $1, false)`, strconv.Itoa(timeoutMs)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetTimeout)
+		defer cancel()
+
+		if _, err := conn.Exec(ctx, `RESET statement_timeout`); err != nil {
+			conn.Conn().Close(ctx)
+		}
+	}, nil
+}
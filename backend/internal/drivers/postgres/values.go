@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonValue converts a value decoded by pgx into something that encodes
+// cleanly as JSON (uuids as strings, intervals and times as text, ...).
+// bytea values become \x hex strings, the form encodeParam takes them back
+// in.
+func jsonValue(v any) any {
+	switch val := v.(type) {
+	case []byte:
+		return byteaHex(val)
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", val[0:4], val[4:6], val[6:8], val[8:10], val[10:16])
+	case []any:
+		for i := range val {
+			val[i] = jsonValue(val[i])
+		}
+		return val
+	case json.Marshaler, encoding.TextMarshaler:
+		return val
+	case driver.Valuer:
+		if dv, err := val.Value(); err == nil {
+			return dv
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{name: "bytea", value: []byte{0xde, 0xad}, want: `\xdead`},
+		{name: "empty bytea", value: []byte{}, want: `\x`},
+		{name: "bytea array", value: []any{[]byte{0x01}, nil}, want: []any{`\x01`, nil}},
+		{name: "uuid", value: [16]byte{0: 0x12, 15: 0x34}, want: "12000000-0000-0000-0000-000000000034"},
+		{name: "text", value: "a", want: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteaRoundTrip(t *testing.T) {
+	read := []byte{0x00, 0x5c, 0x78, 0xff}
+
+	// A bytea value sent back as it was read reaches the server unchanged.
+	got, err := encodeParam(jsonValue(read), "bytea")
+	if err != nil {
+		t.Fatalf("encodeParam() error = %v", err)
+	}
+	if want := `\x005c78ff`; string(got) != want {
+		t.Errorf("encodeParam() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package activity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+var ErrNotFound = errors.New("query not found")
+
+// Tracker keeps track of the queries currently running against the database
+// so they can be listed and cancelled from another request.
+type Tracker struct {
+	mu      sync.Mutex
+	queries map[string]*entry
+}
+
+type entry struct {
+	tracker *Tracker
+	info    models.RunningQuery
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type ctxKey struct{}
+
+type idKey struct{}
+
+// IDHeader is the response header holding the id a request's query is
+// tracked under.
+const IDHeader = "X-Query-Id"
+
+func NewTracker() *Tracker {
+	return &Tracker{queries: map[string]*entry{}}
+}
+
+// Start registers a query and returns a context that is cancelled when the
+// query is cancelled through the tracker. The returned function must be
+// called once the query has finished.
+//
+// The query is tracked under the id given to ctx by WithID, or a new one.
+func (t *Tracker) Start(ctx context.Context, sql string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	id := ID(ctx)
+	if id == "" {
+		id = newID()
+	}
+
+	e := &entry{
+		tracker: t,
+		info: models.RunningQuery{
+			ID:        id,
+			SQL:       sql,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	t.mu.Lock()
+	t.queries[e.info.ID] = e
+	t.mu.Unlock()
+
+	finish := func() {
+		t.mu.Lock()
+		delete(t.queries, e.info.ID)
+		t.mu.Unlock()
+
+		close(e.done)
+		cancel()
+	}
+
+	return context.WithValue(ctx, ctxKey{}, e), finish
+}
+
+// List returns the running queries, oldest first.
+func (t *Tracker) List() []models.RunningQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	queries := make([]models.RunningQuery, 0, len(t.queries))
+	for _, e := range t.queries {
+		queries = append(queries, e.info)
+	}
+
+	slices.SortFunc(queries, func(a, b models.RunningQuery) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	return queries
+}
+
+// Cancel cancels the context of a running query. The returned channel is
+// closed once the query has returned.
+func (t *Tracker) Cancel(id string) (models.RunningQuery, <-chan struct{}, error) {
+	t.mu.Lock()
+	e, ok := t.queries[id]
+	t.mu.Unlock()
+
+	if !ok {
+		return models.RunningQuery{}, nil, ErrNotFound
+	}
+
+	e.cancel()
+
+	t.mu.Lock()
+	info := e.info
+	t.mu.Unlock()
+
+	return info, e.done, nil
+}
+
+// SetPID records the backend process id serving the query tracked in ctx,
+// if any. The returned function forgets it again and must be called before
+// the connection goes back to the pool, so cancelling the query never
+// reaches a backend serving another request.
+func SetPID(ctx context.Context, pid uint32) func() {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return func() {}
+	}
+
+	e.tracker.mu.Lock()
+	e.info.PID = pid
+	e.tracker.mu.Unlock()
+
+	return func() {
+		e.tracker.mu.Lock()
+		if e.info.PID == pid {
+			e.info.PID = 0
+		}
+		e.tracker.mu.Unlock()
+	}
+}
+
+// WithID picks the id the query run for a request will be tracked under,
+// so it can be handed to the client before the query has finished.
+func WithID(ctx context.Context) (context.Context, string) {
+	id := newID()
+	return context.WithValue(ctx, idKey{}, id), id
+}
+
+// ID returns the id given to ctx by WithID, if any.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
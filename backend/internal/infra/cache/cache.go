@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache keeps values for a limited time, loading them again once they
+// expire. Failed loads are not cached.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]entry
+}
+
+type entry struct {
+	value     any
+	expiresAt time.Time
+}
+
+func New(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, entries: map[string]entry{}}
+}
+
+// Get returns the value cached under key, calling load when there is none
+// or it expired. Concurrent misses may load the same key more than once.
+func (c *Cache) Get(key string, load func() (any, error)) (any, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(e.expiresAt) {
+		return e.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return value, nil
+}
@@ -3,7 +3,11 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/notices"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,6 +19,7 @@ type DBConnConfig struct {
 	Port     string
 	Db       string
 	SSLMode  string
+	ReadOnly bool
 }
 
 func NewPool(data DBConnConfig) (*pgxpool.Pool, error) {
@@ -22,8 +27,7 @@ func NewPool(data DBConnConfig) (*pgxpool.Pool, error) {
 		data.SSLMode = "disable"
 	}
 
-	pool, err := pgxpool.New(
-		context.Background(),
+	config, err := pgxpool.ParseConfig(
 		fmt.Sprintf(
 			"%s://%s:%s@%s:%s/%s?sslmode=%s",
 			data.Type,
@@ -39,5 +43,28 @@ func NewPool(data DBConnConfig) (*pgxpool.Pool, error) {
 		return nil, err
 	}
 
+	// In read-only mode every session starts with read-only transactions.
+	if data.ReadOnly {
+		config.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+
+	// Notices are handed to whoever listens to the connection, so they can
+	// be returned with the query that caused them.
+	config.ConnConfig.OnNotice = notices.Handle
+
+	// Cancelling a query's context sends a cancel request to the server
+	// instead of only dropping the connection, so the backend stops too.
+	config.ConnConfig.BuildContextWatcherHandler = func(pgConn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{
+			Conn:          pgConn,
+			DeadlineDelay: 5 * time.Second,
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+
 	return pool, nil
 }
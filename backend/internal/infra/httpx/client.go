@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the address of the client, as set by middleware.RealIP.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// ClientUser returns the user name forwarded by an authenticating proxy, or
+// the basic auth user name, if any.
+func ClientUser(r *http.Request) string {
+	if user := r.Header.Get("X-Forwarded-User"); user != "" {
+		return user
+	}
+
+	user, _, _ := r.BasicAuth()
+	return user
+}
@@ -0,0 +1,60 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+// WriteError answers with the HTTP status matching err and, when it was
+// reported by the database, the details of the error. A row conflict
+// answers with the current contents of the row.
+func WriteError(w http.ResponseWriter, err error) {
+	status := ErrorStatus(err)
+
+	var data any
+	var conflict *models.RowConflictError
+	if errors.As(err, &conflict) {
+		data = conflict.Row
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  status,
+		Message: err.Error(),
+		Data:    data,
+		Error:   pgerror.Details(err),
+	})
+}
+
+func ErrorStatus(err error) int {
+	if errors.Is(err, models.ErrReadOnly) || errors.Is(err, models.ErrReadOnlyRelation) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, models.ErrInvalidFilter) ||
+		errors.Is(err, models.ErrInvalidSort) ||
+		errors.Is(err, models.ErrInvalidCursor) ||
+		errors.Is(err, models.ErrInvalidKey) ||
+		errors.Is(err, models.ErrInvalidRow) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, models.ErrTableNotFound) || errors.Is(err, models.ErrRowNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, models.ErrAmbiguousRow) || errors.Is(err, models.ErrRowChanged) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, models.ErrTooManySubscribers) || errors.Is(err, sessions.ErrTooMany) {
+		return http.StatusServiceUnavailable
+	}
+	// A busy session is running another query; it can be retried once that
+	// one is done.
+	if errors.Is(err, sessions.ErrBusy) {
+		return http.StatusLocked
+	}
+	return pgerror.Status(err)
+}
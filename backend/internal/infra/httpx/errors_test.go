@@ -0,0 +1,40 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "read-only server", err: models.ErrReadOnly, want: http.StatusForbidden},
+		{name: "read-only relation", err: models.ErrReadOnlyRelation, want: http.StatusForbidden},
+		{name: "invalid row", err: fmt.Errorf("%w: unknown column", models.ErrInvalidRow), want: http.StatusBadRequest},
+		{name: "table not found", err: fmt.Errorf("%w: \"public\".\"nope\"", models.ErrTableNotFound), want: http.StatusNotFound},
+		{name: "row not found", err: models.ErrRowNotFound, want: http.StatusNotFound},
+		{name: "row changed", err: &models.RowConflictError{}, want: http.StatusConflict},
+		{name: "too many subscribers", err: models.ErrTooManySubscribers, want: http.StatusServiceUnavailable},
+		{name: "too many sessions", err: sessions.ErrTooMany, want: http.StatusServiceUnavailable},
+		{name: "busy session", err: sessions.ErrBusy, want: http.StatusLocked},
+		{name: "undefined table", err: &pgconn.PgError{Code: "42P01"}, want: http.StatusNotFound},
+		{name: "other error", err: errors.New("boom"), want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorStatus(tt.err); got != tt.want {
+				t.Errorf("ErrorStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
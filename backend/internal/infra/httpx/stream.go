@@ -0,0 +1,144 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+const (
+	StreamNDJSON = "ndjson"
+	StreamSSE    = "sse"
+)
+
+// progressInterval is how often a progress event is sent while rows are
+// being streamed.
+const progressInterval = time.Second
+
+// StreamWriter writes a result set to the client as it is produced, either
+// as newline-delimited JSON or as Server-Sent Events. Every message carries
+// a type: "columns", "row", "progress", "done" or "error".
+type StreamWriter struct {
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	format       string
+	started      bool
+	rows         int64
+	startedAt    time.Time
+	lastProgress time.Time
+}
+
+type streamMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type streamProgress struct {
+	Rows      int64   `json:"rows"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+}
+
+func ValidStreamFormat(format string) bool {
+	return format == StreamNDJSON || format == StreamSSE
+}
+
+func NewStreamWriter(w http.ResponseWriter, format string) *StreamWriter {
+	now := time.Now()
+	return &StreamWriter{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		format:       format,
+		startedAt:    now,
+		lastProgress: now,
+	}
+}
+
+// Started reports whether anything was written yet. Until then errors can
+// still be answered with a regular JSON response.
+func (s *StreamWriter) Started() bool {
+	return s.started
+}
+
+func (s *StreamWriter) Columns(columns []models.QueryColumn) error {
+	return s.send("columns", columns, true)
+}
+
+func (s *StreamWriter) Row(values []any) error {
+	if err := s.send("row", values, false); err != nil {
+		return err
+	}
+	s.rows++
+
+	if time.Since(s.lastProgress) >= progressInterval {
+		s.lastProgress = time.Now()
+		return s.send("progress", s.progress(), true)
+	}
+
+	return nil
+}
+
+// Done ends the stream with the final result of the query.
+func (s *StreamWriter) Done(res *models.ApiResponse) error {
+	return s.send("done", map[string]any{
+		"progress": s.progress(),
+		"result":   res,
+	}, true)
+}
+
+// Error ends the stream with an error. Headers are already sent at this
+// point, so the error can only be reported in-band.
+func (s *StreamWriter) Error(err error) error {
+	return s.send("error", models.ApiResponse{
+		Status:  ErrorStatus(err),
+		Message: err.Error(),
+		Error:   pgerror.Details(err),
+	}, true)
+}
+
+func (s *StreamWriter) progress() streamProgress {
+	return streamProgress{
+		Rows:      s.rows,
+		ElapsedMs: float64(time.Since(s.startedAt).Microseconds()) / 1000,
+	}
+}
+
+func (s *StreamWriter) send(kind string, data any, flush bool) error {
+	payload, err := json.Marshal(streamMessage{Type: kind, Data: data})
+	if err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.started = true
+
+		switch s.format {
+		case StreamSSE:
+			s.w.Header().Set("Content-Type", "text/event-stream")
+		default:
+			s.w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	switch s.format {
+	case StreamSSE:
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", kind, payload)
+	default:
+		_, err = fmt.Fprintf(s.w, "%s\n", payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	if flush {
+		return s.rc.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package limits
+
+import "github.com/euandresimoes/visualdb-go.git/internal/models"
+
+// Config holds the server-wide query limits: the defaults used when a
+// request doesn't ask for a limit and the ceilings a request can't go past.
+// Zero means no limit.
+type Config struct {
+	DefaultTimeoutMs int
+	MaxTimeoutMs     int
+	DefaultMaxRows   int
+	MaxRows          int
+
+	// StreamMaxRows caps the rows of a streamed result, which is not
+	// bounded by MaxRows.
+	StreamMaxRows int
+}
+
+// Resolve fills in the limits a request left out with the defaults and
+// lowers the ones above the ceilings.
+func (c Config) Resolve(req models.QueryLimits) models.QueryLimits {
+	return models.QueryLimits{
+		TimeoutMs: resolve(req.TimeoutMs, c.DefaultTimeoutMs, c.MaxTimeoutMs),
+		MaxRows:   resolve(req.MaxRows, c.DefaultMaxRows, c.MaxRows),
+	}
+}
+
+func resolve(value int, def int, ceiling int) int {
+	if value <= 0 {
+		value = def
+	}
+	if ceiling > 0 && (value <= 0 || value > ceiling) {
+		value = ceiling
+	}
+	return value
+}
+
+// ResolveStream resolves the limits of a streamed result, meant to carry any
+// number of rows for as long as it takes: no default applies, only the
+// limits the request asked for, lowered to the timeout ceiling and to
+// StreamMaxRows.
+func (c Config) ResolveStream(req models.QueryLimits) models.QueryLimits {
+	var l models.QueryLimits
+	if req.TimeoutMs > 0 {
+		l.TimeoutMs = resolve(req.TimeoutMs, 0, c.MaxTimeoutMs)
+	}
+	l.MaxRows = resolve(req.MaxRows, 0, c.StreamMaxRows)
+	return l
+}
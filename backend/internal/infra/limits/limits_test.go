@@ -0,0 +1,62 @@
+package limits
+
+import (
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+var config = Config{
+	DefaultTimeoutMs: 60_000,
+	MaxTimeoutMs:     600_000,
+	DefaultMaxRows:   10_000,
+	MaxRows:          100_000,
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.QueryLimits
+		want models.QueryLimits
+	}{
+		{name: "defaults", want: models.QueryLimits{TimeoutMs: 60_000, MaxRows: 10_000}},
+		{name: "asked", req: models.QueryLimits{TimeoutMs: 5_000, MaxRows: 50}, want: models.QueryLimits{TimeoutMs: 5_000, MaxRows: 50}},
+		{name: "above the ceilings", req: models.QueryLimits{TimeoutMs: 700_000, MaxRows: 200_000}, want: models.QueryLimits{TimeoutMs: 600_000, MaxRows: 100_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.Resolve(tt.req); got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Streams and exports carry whole tables: the defaults, meant for results
+// read at once, don't apply to them.
+func TestResolveStream(t *testing.T) {
+	capped := config
+	capped.StreamMaxRows = 1_000_000
+
+	tests := []struct {
+		name   string
+		config Config
+		req    models.QueryLimits
+		want   models.QueryLimits
+	}{
+		{name: "no defaults", config: config, want: models.QueryLimits{}},
+		{name: "asked", config: config, req: models.QueryLimits{TimeoutMs: 5_000, MaxRows: 200_000}, want: models.QueryLimits{TimeoutMs: 5_000, MaxRows: 200_000}},
+		{name: "timeout above the ceiling", config: config, req: models.QueryLimits{TimeoutMs: 700_000}, want: models.QueryLimits{TimeoutMs: 600_000}},
+		{name: "rows capped", config: capped, want: models.QueryLimits{MaxRows: 1_000_000}},
+		{name: "rows above the cap", config: capped, req: models.QueryLimits{MaxRows: 2_000_000}, want: models.QueryLimits{MaxRows: 1_000_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.ResolveStream(tt.req); got != tt.want {
+				t.Errorf("ResolveStream() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
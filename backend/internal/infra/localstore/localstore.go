@@ -0,0 +1,20 @@
+package localstore
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Open opens (creating it if needed) the embedded database visualdb keeps
+// its own data in, such as query history. It lives in dir, never in the
+// database being browsed.
+func Open(dir string) (*bbolt.DB, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return bbolt.Open(filepath.Join(dir, "visualdb.db"), 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+}
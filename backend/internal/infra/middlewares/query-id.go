@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+)
+
+// QueryID picks the id the query of the request will be tracked under and
+// sends it in the X-Query-Id header right away, so the client can cancel
+// the query while it is still running.
+func QueryID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, id := activity.WithID(r.Context())
+		w.Header().Set(activity.IDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
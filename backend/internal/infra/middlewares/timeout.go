@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Timeout cancels the context of requests running longer than timeoutMs,
+// for the routes whose queries get no statement timeout of their own. Zero
+// means no limit.
+func Timeout(timeoutMs int) func(http.Handler) http.Handler {
+	if timeoutMs <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.Timeout(time.Duration(timeoutMs) * time.Millisecond)
+}
@@ -0,0 +1,65 @@
+package notices
+
+import (
+	"sync"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// listeners maps the connections being listened to to their collector.
+var listeners sync.Map
+
+// Collector gathers the notices (NOTICE, WARNING, INFO, RAISE output...)
+// a connection receives while it is listened to.
+type Collector struct {
+	conn    *pgconn.PgConn
+	mu      sync.Mutex
+	notices []models.Notice
+}
+
+// Handle is installed as the notice handler of every connection. Notices
+// received by a connection nobody listens to are dropped.
+func Handle(conn *pgconn.PgConn, n *pgconn.Notice) {
+	c, ok := listeners.Load(conn)
+	if !ok {
+		return
+	}
+
+	c.(*Collector).add(models.Notice{
+		Severity: n.Severity,
+		Code:     n.Code,
+		Message:  n.Message,
+		Detail:   n.Detail,
+		Hint:     n.Hint,
+		Where:    n.Where,
+	})
+}
+
+// Listen starts collecting the notices received by conn. Stop must be
+// called once done with the connection.
+func Listen(conn *pgconn.PgConn) *Collector {
+	c := &Collector{conn: conn}
+	listeners.Store(conn, c)
+	return c
+}
+
+func (c *Collector) Stop() {
+	listeners.CompareAndDelete(c.conn, c)
+}
+
+// Take returns the notices received since the last call, in order.
+func (c *Collector) Take() []models.Notice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	notices := c.notices
+	c.notices = nil
+	return notices
+}
+
+func (c *Collector) add(n models.Notice) {
+	c.mu.Lock()
+	c.notices = append(c.notices, n)
+	c.mu.Unlock()
+}
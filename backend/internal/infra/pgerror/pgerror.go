@@ -0,0 +1,80 @@
+package pgerror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// statuses maps SQLSTATE codes, or their two-character class, to the HTTP
+// status answered for them. Exact codes take precedence over classes.
+var statuses = map[string]int{
+	"42P01": http.StatusNotFound,  // undefined_table
+	"3F000": http.StatusNotFound,  // invalid_schema_name
+	"42501": http.StatusForbidden, // insufficient_privilege
+	"25006": http.StatusForbidden, // read_only_sql_transaction
+	"57014": http.StatusRequestTimeout,
+	"23505": http.StatusConflict, // unique_violation
+
+	"22": http.StatusBadRequest,         // data_exception
+	"23": http.StatusConflict,           // integrity_constraint_violation
+	"42": http.StatusBadRequest,         // syntax_error_or_access_rule_violation
+	"08": http.StatusServiceUnavailable, // connection_exception
+	"53": http.StatusServiceUnavailable, // insufficient_resources
+}
+
+// Status returns the HTTP status for err: the one mapped to its SQLSTATE
+// when it comes from the server, 409 otherwise.
+func Status(err error) int {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return http.StatusConflict
+	}
+
+	if status, ok := statuses[pgErr.Code]; ok {
+		return status
+	}
+	if len(pgErr.Code) == 5 {
+		if status, ok := statuses[pgErr.Code[:2]]; ok {
+			return status
+		}
+	}
+
+	return http.StatusConflict
+}
+
+// Details returns the fields of err reported by the server, or nil when err
+// doesn't come from the server.
+func Details(err error) *models.ApiError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	return &models.ApiError{
+		Code:           pgErr.Code,
+		Severity:       pgErr.Severity,
+		Message:        pgErr.Message,
+		Detail:         pgErr.Detail,
+		Hint:           pgErr.Hint,
+		Position:       pgErr.Position,
+		Where:          pgErr.Where,
+		SchemaName:     pgErr.SchemaName,
+		TableName:      pgErr.TableName,
+		ColumnName:     pgErr.ColumnName,
+		DataTypeName:   pgErr.DataTypeName,
+		ConstraintName: pgErr.ConstraintName,
+	}
+}
+
+// ShiftPosition moves the position reported for err by offset characters,
+// for statements that were run as part of a larger text. Errors without a
+// position are left alone.
+func ShiftPosition(err error, offset int) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Position > 0 && int(pgErr.Position)+offset > 0 {
+		pgErr.Position += int32(offset)
+	}
+}
@@ -0,0 +1,201 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrBusy     = errors.New("session is busy running another query")
+	ErrTooMany  = errors.New("too many open sessions")
+)
+
+// closeTimeout bounds how long closing the connection of a session may
+// take.
+const closeTimeout = 5 * time.Second
+
+// Manager keeps the sessions opened from the query editor. Each session
+// holds a dedicated connection, so a transaction begun in one request can
+// be committed or rolled back in a later one. Sessions left idle for longer
+// than the idle timeout are closed, which rolls back their transaction.
+type Manager struct {
+	mu          sync.Mutex
+	sessions    map[string]*session
+	idleTimeout time.Duration
+	maxSessions int
+}
+
+type session struct {
+	info models.QuerySession
+	conn *pgxpool.Conn
+}
+
+// NewManager returns a manager allowing up to maxSessions sessions at once
+// (no limit when zero) and starts closing the ones idle for idleTimeout.
+func NewManager(idleTimeout time.Duration, maxSessions int) *Manager {
+	m := &Manager{
+		sessions:    map[string]*session{},
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+	}
+
+	go m.expire()
+
+	return m
+}
+
+// Open registers a session holding conn. The connection is closed, never
+// returned to the pool, when the session ends.
+func (m *Manager) Open(conn *pgxpool.Conn) (models.QuerySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.maxSessions > 0 && len(m.sessions) >= m.maxSessions {
+		return models.QuerySession{}, ErrTooMany
+	}
+
+	now := time.Now()
+	s := &session{
+		info: models.QuerySession{
+			ID:          newID(),
+			PID:         conn.Conn().PgConn().PID(),
+			Transaction: Transaction(conn),
+			CreatedAt:   now,
+			LastUsedAt:  now,
+			ExpiresAt:   now.Add(m.idleTimeout),
+		},
+		conn: conn,
+	}
+	m.sessions[s.info.ID] = s
+
+	return s.info, nil
+}
+
+// Use hands out the connection of a session for one request. The returned
+// function must be called once the request is done with it; until then the
+// session is busy and other requests get ErrBusy.
+func (m *Manager) Use(id string) (*pgxpool.Conn, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	if s.info.Busy {
+		return nil, nil, ErrBusy
+	}
+	s.info.Busy = true
+
+	release := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		now := time.Now()
+		s.info.Busy = false
+		s.info.Transaction = Transaction(s.conn)
+		s.info.LastUsedAt = now
+		s.info.ExpiresAt = now.Add(m.idleTimeout)
+	}
+
+	return s.conn, release, nil
+}
+
+// List returns the open sessions, oldest first.
+func (m *Manager) List() []models.QuerySession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := make([]models.QuerySession, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s.info)
+	}
+
+	slices.SortFunc(sessions, func(a, b models.QuerySession) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return sessions
+}
+
+// Close ends a session, rolling back its open transaction, if any. A busy
+// session has to finish (or be cancelled) first.
+func (m *Manager) Close(id string) (models.QuerySession, error) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if ok && !s.info.Busy {
+		delete(m.sessions, id)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return models.QuerySession{}, ErrNotFound
+	}
+	if s.info.Busy {
+		return models.QuerySession{}, ErrBusy
+	}
+
+	closeConn(s.conn)
+
+	return s.info, nil
+}
+
+func (m *Manager) expire() {
+	ticker := time.NewTicker(max(m.idleTimeout/4, time.Second))
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		var expired []*session
+
+		m.mu.Lock()
+		for id, s := range m.sessions {
+			if !s.info.Busy && now.After(s.info.ExpiresAt) {
+				delete(m.sessions, id)
+				expired = append(expired, s)
+			}
+		}
+		m.mu.Unlock()
+
+		for _, s := range expired {
+			closeConn(s.conn)
+		}
+	}
+}
+
+// Transaction reports the transaction state of a session connection. It
+// must not be called while a query is running on conn.
+func Transaction(conn *pgxpool.Conn) string {
+	switch conn.Conn().PgConn().TxStatus() {
+	case 'T':
+		return models.SessionInTransaction
+	case 'E':
+		return models.SessionFailed
+	default:
+		return models.SessionIdle
+	}
+}
+
+// closeConn takes the connection out of the pool and closes it. The server
+// rolls back whatever transaction was open, and settings or temporary
+// tables created in the session never leak to other requests.
+func closeConn(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	conn.Hijack().Close(ctx)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -4,4 +4,34 @@ type ApiResponse struct {
 	Status  any    `json:"status"`
 	Message string `json:"message"`
 	Data    any    `json:"data"`
+
+	// Truncated is set when rows were left out because of a row limit.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// QueryID is the id the query was tracked under while it ran, as sent
+	// in the X-Query-Id header.
+	QueryID string `json:"query_id,omitempty"`
+
+	// Pagination describes the page returned when browsing rows.
+	Pagination *Pagination `json:"pagination,omitempty"`
+
+	// Error holds the details of an error reported by the database.
+	Error *ApiError `json:"error,omitempty"`
+}
+
+// ApiError is an error reported by the database server. Position is the
+// 1-based character offset of the error in the query that was sent.
+type ApiError struct {
+	Code           string `json:"code"`
+	Severity       string `json:"severity"`
+	Message        string `json:"message"`
+	Detail         string `json:"detail,omitempty"`
+	Hint           string `json:"hint,omitempty"`
+	Position       int32  `json:"position,omitempty"`
+	Where          string `json:"where,omitempty"`
+	SchemaName     string `json:"schema_name,omitempty"`
+	TableName      string `json:"table_name,omitempty"`
+	ColumnName     string `json:"column_name,omitempty"`
+	DataTypeName   string `json:"data_type_name,omitempty"`
+	ConstraintName string `json:"constraint_name,omitempty"`
 }
@@ -6,4 +6,11 @@ type ColumnModel struct {
 	IsNullable    *string `json:"is_nullable"`
 	ColumnDefault *string `json:"column_default"`
 	IsPrimaryKey  bool    `json:"is_primary_key"`
+
+	// IsRowKey marks the columns identifying a row when updating or
+	// deleting it: the primary key or, without one, a unique key on NOT
+	// NULL columns. When no column of a table is marked rows are identified
+	// by their ctid, which GetRows then returns with the other columns.
+	// Views and other relations without a ctid have no row key.
+	IsRowKey bool `json:"is_row_key"`
 }
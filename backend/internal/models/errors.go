@@ -0,0 +1,59 @@
+package models
+
+import "errors"
+
+var ErrReadOnly = errors.New("the server is in read-only mode")
+
+// ErrInvalidFilter is wrapped by the errors reporting a malformed row
+// filter.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ErrInvalidSort is wrapped by the errors reporting a malformed row sort.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// ErrInvalidCursor is wrapped by the errors reporting a cursor that cannot
+// be used to read a page of rows.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidKey is wrapped by the errors reporting a row key that does not
+// match the key columns of the table.
+var ErrInvalidKey = errors.New("invalid row key")
+
+// ErrInvalidRow is wrapped by the errors reporting row values that cannot
+// be written to the table.
+var ErrInvalidRow = errors.New("invalid row")
+
+// ErrTableNotFound is returned when the table (or view) does not exist.
+var ErrTableNotFound = errors.New("table not found")
+
+// ErrReadOnlyRelation is returned when changing a row of a relation
+// without row versions, such as a view or a foreign table.
+var ErrReadOnlyRelation = errors.New("the rows of this relation have no version and cannot be updated or deleted")
+
+// ErrRowNotFound is returned when no row matches a row key.
+var ErrRowNotFound = errors.New("no row matches the key")
+
+// ErrAmbiguousRow is returned when a row key matches more than one row.
+var ErrAmbiguousRow = errors.New("the key matches more than one row")
+
+// ErrRowChanged is wrapped by RowConflictError, returned when a row was
+// changed since the version given to change it was read.
+var ErrRowChanged = errors.New("the row was changed since it was read")
+
+// RowConflictError reports a row edited with an outdated version. Row holds
+// its current contents, version included.
+type RowConflictError struct {
+	Row map[string]any
+}
+
+func (e *RowConflictError) Error() string {
+	return ErrRowChanged.Error()
+}
+
+func (e *RowConflictError) Unwrap() error {
+	return ErrRowChanged
+}
+
+// ErrTooManySubscribers is returned when the LISTEN connection already has
+// as many subscribers as allowed.
+var ErrTooManySubscribers = errors.New("too many notification subscribers")
@@ -0,0 +1,50 @@
+package models
+
+type ExplainRequest struct {
+	Query   string       `json:"query"`
+	Params  []QueryParam `json:"params"`
+	Analyze bool         `json:"analyze"`
+	Buffers bool         `json:"buffers"`
+
+	// TimeoutMs bounds the statement; max_rows doesn't apply to a plan.
+	QueryLimits
+}
+
+type PlanBuffers struct {
+	SharedHit     int64 `json:"shared_hit"`
+	SharedRead    int64 `json:"shared_read"`
+	SharedDirtied int64 `json:"shared_dirtied"`
+	SharedWritten int64 `json:"shared_written"`
+	TempRead      int64 `json:"temp_read"`
+	TempWritten   int64 `json:"temp_written"`
+}
+
+type PlanNode struct {
+	NodeType          string       `json:"node_type"`
+	Relation          string       `json:"relation,omitempty"`
+	Schema            string       `json:"schema,omitempty"`
+	Alias             string       `json:"alias,omitempty"`
+	Index             string       `json:"index,omitempty"`
+	JoinType          string       `json:"join_type,omitempty"`
+	Condition         string       `json:"condition,omitempty"`
+	Filter            string       `json:"filter,omitempty"`
+	StartupCost       float64      `json:"startup_cost"`
+	TotalCost         float64      `json:"total_cost"`
+	EstimatedRows     float64      `json:"estimated_rows"`
+	ActualRows        *float64     `json:"actual_rows,omitempty"`
+	Loops             *float64     `json:"loops,omitempty"`
+	TotalTimeMs       *float64     `json:"total_time_ms,omitempty"`
+	ExclusiveTimeMs   *float64     `json:"exclusive_time_ms,omitempty"`
+	MisestimateFactor *float64     `json:"misestimate_factor,omitempty"`
+	Buffers           *PlanBuffers `json:"buffers,omitempty"`
+	Slowest           bool         `json:"slowest"`
+	MostMisestimated  bool         `json:"most_misestimated"`
+	Children          []*PlanNode  `json:"children"`
+}
+
+type ExplainResult struct {
+	Plan            *PlanNode `json:"plan"`
+	PlanningTimeMs  *float64  `json:"planning_time_ms,omitempty"`
+	ExecutionTimeMs *float64  `json:"execution_time_ms,omitempty"`
+	Raw             any       `json:"raw"`
+}
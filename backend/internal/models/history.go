@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type HistoryEntry struct {
+	ID           uint64       `json:"id"`
+	Query        string       `json:"query"`
+	Params       []QueryParam `json:"params,omitempty"`
+	StartedAt    time.Time    `json:"started_at"`
+	DurationMs   float64      `json:"duration_ms"`
+	RowsAffected int64        `json:"rows_affected"`
+	RowsReturned int64        `json:"rows_returned"`
+	Error        string       `json:"error,omitempty"`
+	User         string       `json:"user,omitempty"`
+	IP           string       `json:"ip,omitempty"`
+}
+
+type HistoryPage struct {
+	Entries []HistoryEntry `json:"entries"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+	Total   int            `json:"total"`
+}
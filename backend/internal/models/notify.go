@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Actions a subscriber can send over its LISTEN WebSocket.
+const (
+	ListenActionListen   = "listen"
+	ListenActionUnlisten = "unlisten"
+)
+
+// ListenCommand adds or removes a channel of a subscriber.
+type ListenCommand struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+}
+
+// Notification is a NOTIFY received on one of the channels listened on.
+type Notification struct {
+	Channel    string    `json:"channel"`
+	Payload    string    `json:"payload"`
+	PID        uint32    `json:"pid"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+type NotifyRequest struct {
+	Channel string `json:"channel"`
+	Payload string `json:"payload"`
+}
+
+// NotificationSink receives what happens on the connection of a subscriber:
+// the channels listened on after each change, the notifications and the
+// commands that failed.
+type NotificationSink interface {
+	Listening(channels []string) error
+	Notification(n Notification) error
+	Error(err error) error
+}
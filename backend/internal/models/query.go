@@ -0,0 +1,136 @@
+package models
+
+import "time"
+
+type QueryColumn struct {
+	Name     string `json:"name"`
+	TypeOID  uint32 `json:"type_oid"`
+	TypeName string `json:"type_name"`
+}
+
+type QueryResult struct {
+	Columns      []QueryColumn `json:"columns"`
+	Rows         [][]any       `json:"rows"`
+	Command      string        `json:"command"`
+	RowsAffected int64         `json:"rows_affected"`
+	Truncated    bool          `json:"truncated"`
+}
+
+// How the statements of a script run: all in one transaction, skipping the
+// rest after a failure, or each on its own. Without a mode, a script of a
+// single statement runs on its own, so statements refusing to run inside a
+// transaction (VACUUM, CREATE INDEX CONCURRENTLY) work, and longer scripts
+// run in a transaction.
+const (
+	QueryModeTransaction = "transaction"
+	QueryModeContinue    = "continue"
+)
+
+type QueryParam struct {
+	Value any    `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// QueryLimits bounds how long each statement may run and how many rows are
+// read from each result set. Zero means no limit.
+type QueryLimits struct {
+	TimeoutMs int `json:"timeout_ms"`
+	MaxRows   int `json:"max_rows"`
+}
+
+type QueryRequest struct {
+	Query  string       `json:"query"`
+	Mode   string       `json:"mode"`
+	Params []QueryParam `json:"params"`
+	Stream string       `json:"stream"`
+	QueryLimits
+
+	// SessionID runs the query on the connection of a session, where
+	// transactions can span several requests.
+	SessionID string `json:"session_id"`
+
+	// Who sent the query, recorded in the query history.
+	ClientIP   string `json:"-"`
+	ClientUser string `json:"-"`
+}
+
+type QueryParamType struct {
+	Position int    `json:"position"`
+	TypeOID  uint32 `json:"type_oid"`
+	TypeName string `json:"type_name"`
+}
+
+type StatementDescription struct {
+	Statement string           `json:"statement"`
+	Params    []QueryParamType `json:"params"`
+	Columns   []QueryColumn    `json:"columns"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type StatementResult struct {
+	Statement string `json:"statement"`
+	*QueryResult
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"`
+
+	// ErrorDetails holds the fields of an error reported by the server. Its
+	// position is relative to the whole script.
+	ErrorDetails *ApiError `json:"error_details,omitempty"`
+
+	// Notices are the messages the server sent while running the statement,
+	// in order.
+	Notices []Notice `json:"notices,omitempty"`
+}
+
+// Notice is a message sent by the server without failing the statement,
+// such as RAISE NOTICE output or VACUUM VERBOSE progress.
+type Notice struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	Where    string `json:"where,omitempty"`
+}
+
+type ScriptResult struct {
+	Mode       string            `json:"mode"`
+	Statements []StatementResult `json:"statements"`
+	Failed     int               `json:"failed"`
+	RolledBack bool              `json:"rolled_back"`
+
+	// Transaction is the state of the session's transaction after the
+	// script, when it ran in a session.
+	Transaction string `json:"transaction,omitempty"`
+}
+
+// RowSink receives the rows of a result set one at a time, as they are read
+// from the database.
+type RowSink interface {
+	Columns(columns []QueryColumn) error
+	Row(values []any) error
+}
+
+const (
+	CompletionKeyword  = "keyword"
+	CompletionSchema   = "schema"
+	CompletionTable    = "table"
+	CompletionColumn   = "column"
+	CompletionFunction = "function"
+)
+
+type Completion struct {
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// CompletionResult holds the suggestions for the word at the cursor. From
+// and To delimit, in characters, the text a suggestion replaces.
+type CompletionResult struct {
+	From        int          `json:"from"`
+	To          int          `json:"to"`
+	Suggestions []Completion `json:"suggestions"`
+}
@@ -0,0 +1,170 @@
+package models
+
+// Operators of a row filter condition.
+const (
+	FilterEq       = "eq"
+	FilterNeq      = "neq"
+	FilterLt       = "lt"
+	FilterLte      = "lte"
+	FilterGt       = "gt"
+	FilterGte      = "gte"
+	FilterLike     = "like"
+	FilterNotLike  = "not_like"
+	FilterILike    = "ilike"
+	FilterNotILike = "not_ilike"
+	FilterIn       = "in"
+	FilterNotIn    = "not_in"
+	FilterIsNull   = "is_null"
+	FilterNotNull  = "not_null"
+	FilterBetween  = "between"
+)
+
+// RowFilter is either a group of filters joined by AND or OR, or a single
+// condition comparing Column to Value with Op. Value is a list for in,
+// not_in and between, and unused for is_null and not_null.
+type RowFilter struct {
+	And    []RowFilter `json:"and,omitempty"`
+	Or     []RowFilter `json:"or,omitempty"`
+	Column string      `json:"column,omitempty"`
+	Op     string      `json:"op,omitempty"`
+	Value  any         `json:"value,omitempty"`
+}
+
+// Placement of NULLs in a row sort. The default follows Postgres: last when
+// ascending, first when descending.
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+type RowSort struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+	Nulls  string `json:"nulls,omitempty"`
+}
+
+// How the total number of rows of a page is counted.
+const (
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+	CountNone     = "none"
+)
+
+// How pages of rows are addressed: by number, skipping the rows of the
+// previous pages, or by a cursor holding the sort values of the row the page
+// starts after (or ends before).
+const (
+	PagingOffset = "offset"
+	PagingKeyset = "keyset"
+)
+
+// RowsQuery selects the rows of a table to read and their order. The row
+// key always breaks ties, so pages do not overlap. Page is only
+// used with offset paging and Cursor with keyset paging, where an empty
+// cursor reads the first page.
+type RowsQuery struct {
+	Page   int
+	Limit  int
+	Filter *RowFilter
+	Sort   []RowSort
+	Count  string
+	Paging string
+	Cursor string
+}
+
+// Pagination describes the page of rows returned. HasNext reports whether
+// more rows follow the ones returned. Total counts the rows matching the
+// filter; it is left out when not asked for and only approximate when
+// TotalEstimated is set. With keyset paging, NextCursor and PrevCursor read
+// the pages around this one and are empty when there is none.
+type Pagination struct {
+	Page           int    `json:"page,omitempty"`
+	Limit          int    `json:"limit"`
+	HasNext        bool   `json:"has_next"`
+	Total          *int64 `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
+}
+
+// RowWrite is the result of inserting or updating a row. Row is the row as
+// stored, with the values the server filled in, encoded as GetRows returns
+// rows. It is nil when an insert was skipped on conflict.
+type RowWrite struct {
+	Row          map[string]any `json:"row"`
+	RowsAffected int64          `json:"rows_affected"`
+}
+
+// RowsWrite is the result of inserting several rows at once: the rows as
+// stored, in the order they were given, leaving out those skipped on
+// conflict.
+type RowsWrite struct {
+	Rows         []map[string]any `json:"rows"`
+	RowsAffected int64            `json:"rows_affected"`
+}
+
+// What an insert does with a row conflicting with an existing one on a
+// unique constraint. Without either the insert fails.
+const (
+	OnConflictDoNothing = "do_nothing"
+	OnConflictDoUpdate  = "do_update"
+)
+
+// InsertOptions tell how an insert handles conflicts. The conflict is
+// looked for on the unique constraint named ConflictConstraint or the one
+// on ConflictColumns; do_update needs one of them and sets every inserted
+// column but the conflict columns to its new value.
+type InsertOptions struct {
+	OnConflict         string
+	ConflictConstraint string
+	ConflictColumns    []string
+}
+
+// Kinds of change in a changeset.
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// RowChange is one edit of a changeset. Key and Version identify the row to
+// update or delete, as for a single edit; Data holds the values to insert
+// or set.
+type RowChange struct {
+	Op      string         `json:"op"`
+	Schema  string         `json:"schema"`
+	Table   string         `json:"table"`
+	Key     map[string]any `json:"key,omitempty"`
+	Version string         `json:"version,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+type ChangesetRequest struct {
+	Changes []RowChange `json:"changes"`
+}
+
+type ChangeResult struct {
+	Op           string    `json:"op"`
+	Schema       string    `json:"schema"`
+	Table        string    `json:"table"`
+	RowsAffected int64     `json:"rows_affected"`
+	Error        string    `json:"error,omitempty"`
+	ErrorDetails *ApiError `json:"error_details,omitempty"`
+	Skipped      bool      `json:"skipped,omitempty"`
+
+	// Row is the row as stored by an insert or update.
+	Row map[string]any `json:"row,omitempty"`
+
+	// Current holds the current contents of a row changed since its
+	// version was read.
+	Current map[string]any `json:"current,omitempty"`
+}
+
+// ChangesetResult holds the result of every change, in order. The changes
+// are applied in a single transaction: after the first failure the rest are
+// skipped and nothing is kept.
+type ChangesetResult struct {
+	Changes    []ChangeResult `json:"changes"`
+	Failed     int            `json:"failed"`
+	RolledBack bool           `json:"rolled_back"`
+}
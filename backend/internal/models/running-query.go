@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type RunningQuery struct {
+	ID        string    `json:"id"`
+	SQL       string    `json:"sql"`
+	StartedAt time.Time `json:"started_at"`
+	PID       uint32    `json:"pid"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type SavedQueryParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type SavedQuery struct {
+	ID          uint64            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Query       string            `json:"query"`
+	Folder      string            `json:"folder"`
+	Tags        []string          `json:"tags"`
+	Params      []SavedQueryParam `json:"params"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Transaction states of a session, as reported after each query.
+const (
+	SessionIdle          = "idle"
+	SessionInTransaction = "in_transaction"
+	SessionFailed        = "failed"
+)
+
+type QuerySession struct {
+	ID          string    `json:"id"`
+	PID         uint32    `json:"pid"`
+	Transaction string    `json:"transaction"`
+	Busy        bool      `json:"busy"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
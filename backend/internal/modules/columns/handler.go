@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
 	"github.com/go-chi/chi/v5"
 )
@@ -43,13 +44,9 @@ func (h *Handler) GetColumns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	columns, err := h.Service.GetColumns(schema, table)
+	columns, err := h.Service.GetColumns(r.Context(), schema, table)
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
-		})
+		httpx.WriteError(w, err)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package columns
 
 import (
+	"context"
 	"errors"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
@@ -17,10 +18,10 @@ func NewRepository(db *pgxpool.Pool, dbType string) *Repository {
 	return &Repository{DB: db, DBType: dbType}
 }
 
-func (r *Repository) GetColumns(schema string, table string) (*models.ApiResponse, error) {
+func (r *Repository) GetColumns(ctx context.Context, schema string, table string) (*models.ApiResponse, error) {
 	switch r.DBType {
 	case "postgres":
-		return postgres.GetColumns(r.DB, schema, table)
+		return postgres.GetColumns(ctx, r.DB, schema, table)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
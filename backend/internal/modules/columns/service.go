@@ -1,6 +1,10 @@
 package columns
 
-import "github.com/euandresimoes/visualdb-go.git/internal/models"
+import (
+	"context"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
 
 type Service struct {
 	Repository *Repository
@@ -10,6 +14,6 @@ func NewService(repository *Repository) *Service {
 	return &Service{Repository: repository}
 }
 
-func (s *Service) GetColumns(schema string, table string) (*models.ApiResponse, error) {
-	return s.Repository.GetColumns(schema, table)
+func (s *Service) GetColumns(ctx context.Context, schema string, table string) (*models.ApiResponse, error) {
+	return s.Repository.GetColumns(ctx, schema, table)
 }
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"go.etcd.io/bbolt"
+)
+
+var ErrNotFound = errors.New("history entry not found")
+
+var bucket = []byte("query_history")
+
+// maxEntries is how many entries are kept; older ones are pruned.
+const maxEntries = 10_000
+
+type Repository struct {
+	DB *bbolt.DB
+}
+
+func NewRepository(db *bbolt.DB) (*Repository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{DB: db}, nil
+}
+
+func (r *Repository) AddEntries(entries []models.HistoryEntry) error {
+	return r.DB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		for _, entry := range entries {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			entry.ID = id
+
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key(id), value); err != nil {
+				return err
+			}
+		}
+
+		// Keys are sequential, so the oldest entries come first.
+		var expired [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && b.Sequence()-binary.BigEndian.Uint64(k) >= maxEntries; k, _ = c.Next() {
+			expired = append(expired, k)
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetEntries returns a page of the entries whose query contains search,
+// newest first.
+func (r *Repository) GetEntries(search string, page int, limit int) (*models.HistoryPage, error) {
+	result := &models.HistoryPage{Entries: []models.HistoryEntry{}, Page: page, Limit: limit}
+	search = strings.ToLower(search)
+	offset := (page - 1) * limit
+
+	err := r.DB.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			entry, err := decode(v)
+			if err != nil {
+				return err
+			}
+
+			if search != "" && !strings.Contains(strings.ToLower(entry.Query), search) {
+				continue
+			}
+
+			if result.Total >= offset && len(result.Entries) < limit {
+				result.Entries = append(result.Entries, *entry)
+			}
+			result.Total++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) GetEntry(id uint64) (*models.HistoryEntry, error) {
+	var entry *models.HistoryEntry
+
+	err := r.DB.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		entry, err = decode(v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func key(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// decode keeps numbers as json.Number so parameter values survive the
+// round trip untouched.
+func decode(v []byte) (*models.HistoryEntry, error) {
+	var entry models.HistoryEntry
+
+	decoder := json.NewDecoder(bytes.NewReader(v))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,23 @@
+package history
+
+import "github.com/euandresimoes/visualdb-go.git/internal/models"
+
+type Service struct {
+	Repository *Repository
+}
+
+func NewService(repository *Repository) *Service {
+	return &Service{Repository: repository}
+}
+
+func (s *Service) AddEntries(entries []models.HistoryEntry) error {
+	return s.Repository.AddEntries(entries)
+}
+
+func (s *Service) GetEntries(search string, page int, limit int) (*models.HistoryPage, error) {
+	return s.Repository.GetEntries(search, page, limit)
+}
+
+func (s *Service) GetEntry(id uint64) (*models.HistoryEntry, error) {
+	return s.Repository.GetEntry(id)
+}
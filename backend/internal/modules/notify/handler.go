@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/pgerror"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// pingInterval is how often an idle subscriber is pinged, so proxies
+	// do not drop a WebSocket on a quiet channel.
+	pingInterval = 30 * time.Second
+
+	// writeTimeout bounds how long a message to a subscriber may take.
+	writeTimeout = 10 * time.Second
+)
+
+// upgrader accepts any origin, like the CORS settings of the API.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type Handler struct {
+	Service *Service
+}
+
+// NewHandler mounts the notify routes. In read-only mode listening still
+// works but sending a notification answers 403.
+func NewHandler(service *Service, readOnly bool) http.Handler {
+	h := &Handler{Service: service}
+	r := chi.NewRouter()
+
+	r.Get("/listen", h.Listen)
+
+	if readOnly {
+		r.Post("/", h.ReadOnly)
+	} else {
+		r.Post("/", h.Notify)
+	}
+
+	return r
+}
+
+func (h *Handler) ReadOnly(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusForbidden,
+		Message: models.ErrReadOnly.Error(),
+	})
+}
+
+// Listen upgrades to a WebSocket listening on the channels given as channel
+// query params. The client sends {"action": "listen" | "unlisten",
+// "channel": ...} messages to change them, and receives "listening",
+// "notification" and "error" messages.
+func (h *Handler) Listen(w http.ResponseWriter, r *http.Request) {
+	channels := r.URL.Query()["channel"]
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already answered the request.
+		return
+	}
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sink := &socketSink{ws: ws}
+	commands := make(chan models.ListenCommand)
+
+	go func() {
+		defer cancel()
+		defer close(commands)
+
+		for {
+			_, message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var cmd models.ListenCommand
+			if err := json.Unmarshal(message, &cmd); err != nil {
+				sink.Error(err)
+				continue
+			}
+
+			select {
+			case commands <- cmd:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go sink.ping(ctx)
+
+	if err := h.Service.Listen(ctx, channels, commands, sink); err != nil {
+		sink.Error(err)
+		ws.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, ""),
+			time.Now().Add(writeTimeout),
+		)
+	}
+}
+
+func (h *Handler) Notify(w http.ResponseWriter, r *http.Request) {
+	var bodyData models.NotifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !httpx.Require(w, bodyData.Channel, "channel") {
+		return
+	}
+
+	res, err := h.Service.Notify(r.Context(), bodyData)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// socketSink writes what happens on a subscriber connection to its
+// WebSocket. Every message carries a type, like the streamed results.
+type socketSink struct {
+	mu sync.Mutex
+	ws *websocket.Conn
+}
+
+type socketMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+func (s *socketSink) Listening(channels []string) error {
+	return s.send("listening", channels)
+}
+
+func (s *socketSink) Notification(n models.Notification) error {
+	return s.send("notification", n)
+}
+
+func (s *socketSink) Error(err error) error {
+	return s.send("error", models.ApiResponse{
+		Status:  httpx.ErrorStatus(err),
+		Message: err.Error(),
+		Error:   pgerror.Details(err),
+	})
+}
+
+func (s *socketSink) send(kind string, data any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.ws.WriteJSON(socketMessage{Type: kind, Data: data})
+}
+
+func (s *socketSink) ping(ctx context.Context) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
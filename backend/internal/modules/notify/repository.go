@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	DB       *pgxpool.Pool
+	DBType   string
+	Listener *postgres.Listener
+}
+
+// NewRepository returns a repository whose subscribers share one LISTEN
+// connection, with up to maxSubscribers of them at once (no limit when
+// zero).
+func NewRepository(db *pgxpool.Pool, dbType string, maxSubscribers int) *Repository {
+	return &Repository{
+		DB:       db,
+		DBType:   dbType,
+		Listener: postgres.NewListener(db, maxSubscribers),
+	}
+}
+
+func (r *Repository) Listen(ctx context.Context, channels []string, commands <-chan models.ListenCommand, sink models.NotificationSink) error {
+	switch r.DBType {
+	case "postgres":
+		return r.Listener.Listen(ctx, channels, commands, sink)
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
+func (r *Repository) Notify(ctx context.Context, req models.NotifyRequest) (*models.ApiResponse, error) {
+	switch r.DBType {
+	case "postgres":
+		return postgres.Notify(ctx, r.DB, req)
+	default:
+		return nil, errors.New("unsupported database type")
+	}
+}
@@ -0,0 +1,23 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+type Service struct {
+	Repository *Repository
+}
+
+func NewService(repository *Repository) *Service {
+	return &Service{Repository: repository}
+}
+
+func (s *Service) Listen(ctx context.Context, channels []string, commands <-chan models.ListenCommand, sink models.NotificationSink) error {
+	return s.Repository.Listen(ctx, channels, commands, sink)
+}
+
+func (s *Service) Notify(ctx context.Context, req models.NotifyRequest) (*models.ApiResponse, error) {
+	return s.Repository.Notify(ctx, req)
+}
@@ -2,31 +2,135 @@ package query
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"unicode/utf8"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/limits"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/middlewares"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/euandresimoes/visualdb-go.git/internal/modules/history"
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
 	Service *Service
+	Limits  limits.Config
 }
 
-func NewHandler(service *Service) http.Handler {
-	h := &Handler{Service: service}
+func NewHandler(service *Service, limits limits.Config) http.Handler {
+	h := &Handler{Service: service, Limits: limits}
 	r := chi.NewRouter()
 
 	r.Post("/", h.RunQuery)
+	r.Post("/describe", h.DescribeQuery)
+	r.Post("/explain", h.ExplainQuery)
+	r.With(middlewares.Timeout(limits.DefaultTimeoutMs)).Get("/complete", h.Complete)
+	r.Get("/running", h.GetRunningQueries)
+	r.Delete("/running/{id}", h.CancelQuery)
+	r.Post("/sessions", h.OpenSession)
+	r.Get("/sessions", h.GetSessions)
+	r.Delete("/sessions/{id}", h.CloseSession)
+	r.Get("/history", h.GetHistory)
+	r.Post("/history/{id}/run", h.RerunHistory)
 
 	return r
 }
 
 func (h *Handler) RunQuery(w http.ResponseWriter, r *http.Request) {
-	type Body struct {
-		Query string `json:"query"`
+	var bodyData models.QueryRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !httpx.Require(w, bodyData.Query, "query") {
+		return
+	}
+
+	switch bodyData.Mode {
+	case "", models.QueryModeTransaction, models.QueryModeContinue:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: "mode must be transaction or continue",
+		})
+		return
 	}
 
-	var bodyData Body
+	bodyData.ClientIP = httpx.ClientIP(r)
+	bodyData.ClientUser = httpx.ClientUser(r)
+
+	if bodyData.Stream != "" {
+		if !httpx.ValidStreamFormat(bodyData.Stream) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusBadRequest,
+				Message: "stream must be ndjson or sse",
+			})
+			return
+		}
+
+		bodyData.QueryLimits = h.Limits.ResolveStream(bodyData.QueryLimits)
+
+		stream := httpx.NewStreamWriter(w, bodyData.Stream)
+		res, err := h.Service.StreamQuery(r.Context(), bodyData, stream)
+		if err != nil && stream.Started() {
+			stream.Error(err)
+			return
+		}
+		if errors.Is(err, sessions.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusNotFound,
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			httpx.WriteError(w, err)
+			return
+		}
+
+		res.QueryID = activity.ID(r.Context())
+		stream.Done(res)
+		return
+	}
+
+	bodyData.QueryLimits = h.Limits.Resolve(bodyData.QueryLimits)
+
+	res, err := h.Service.RunQuery(r.Context(), bodyData)
+	if errors.Is(err, sessions.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) DescribeQuery(w http.ResponseWriter, r *http.Request) {
+	var bodyData models.QueryRequest
 	if err := json.NewDecoder(r.Body).Decode(&bodyData); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
@@ -36,16 +140,217 @@ func (h *Handler) RunQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.Service.RunQuery(bodyData.Query)
+	if !httpx.Require(w, bodyData.Query, "query") {
+		return
+	}
+
+	bodyData.QueryLimits = h.Limits.Resolve(bodyData.QueryLimits)
+
+	res, err := h.Service.DescribeQuery(r.Context(), bodyData)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) ExplainQuery(w http.ResponseWriter, r *http.Request) {
+	var bodyData models.ExplainRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !httpx.Require(w, bodyData.Query, "query") {
+		return
+	}
+
+	bodyData.QueryLimits = h.Limits.Resolve(bodyData.QueryLimits)
+
+	res, err := h.Service.ExplainQuery(r.Context(), bodyData)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Complete suggests what may be typed at the cursor, a character offset
+// into sql that defaults to its end.
+func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
+	sql := r.URL.Query().Get("sql")
+
+	cursor := utf8.RuneCountInString(sql)
+	if val := r.URL.Query().Get("cursor"); val != "" {
+		var err error
+		if cursor, err = strconv.Atoi(val); err != nil || cursor < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusBadRequest,
+				Message: "invalid cursor",
+			})
+			return
+		}
+	}
+
+	res, err := h.Service.Complete(r.Context(), sql, cursor)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) GetRunningQueries(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.GetRunningQueries()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) CancelQuery(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	res, err := h.Service.CancelQuery(r.Context(), id)
+	if errors.Is(err, activity.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) OpenSession(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.OpenSession(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.GetSessions()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// CloseSession ends a session, rolling back its open transaction, if any.
+func (h *Handler) CloseSession(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	res, err := h.Service.CloseSession(id)
+	if errors.Is(err, sessions.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	var (
+		search   = r.URL.Query().Get("search")
+		page, _  = strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	)
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	entries, err := h.Service.GetHistory(search, page, limit)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    entries,
+	})
+}
+
+func (h *Handler) RerunHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
+			Status:  http.StatusBadRequest,
+			Message: "invalid history id",
+		})
+		return
+	}
+
+	req := models.QueryRequest{
+		QueryLimits: h.Limits.Resolve(models.QueryLimits{}),
+		ClientIP:    httpx.ClientIP(r),
+		ClientUser:  httpx.ClientUser(r),
+	}
+
+	res, err := h.Service.RerunHistory(r.Context(), id, req)
+	if errors.Is(err, history.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
 
+	res.QueryID = activity.ID(r.Context())
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
@@ -1,27 +1,218 @@
 package query
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"time"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/cache"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/sessions"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// cancelGracePeriod is how long a cancelled query gets to stop on its own
+// before the backend running it is cancelled directly.
+const cancelGracePeriod = 2 * time.Second
+
+// catalogTTL is how long the catalog metadata used for completion is kept
+// before being read again.
+const catalogTTL = time.Minute
+
 type Repository struct {
-	DB     *pgxpool.Pool
-	DBType string
+	DB       *pgxpool.Pool
+	DBType   string
+	Tracker  *activity.Tracker
+	Sessions *sessions.Manager
+	Catalog  *cache.Cache
+	ReadOnly bool
+}
+
+func NewRepository(db *pgxpool.Pool, dbType string, tracker *activity.Tracker, sessions *sessions.Manager, readOnly bool) *Repository {
+	return &Repository{
+		DB:       db,
+		DBType:   dbType,
+		Tracker:  tracker,
+		Sessions: sessions,
+		Catalog:  cache.New(catalogTTL),
+		ReadOnly: readOnly,
+	}
+}
+
+func (r *Repository) RunQuery(ctx context.Context, req models.QueryRequest) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, req.Query)
+	defer finish()
+
+	if req.SessionID != "" {
+		conn, release, err := r.Sessions.Use(req.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		switch r.DBType {
+		case "postgres":
+			return postgres.RunSessionQuery(ctx, conn, req)
+		default:
+			return nil, errors.New("unsupported database type")
+		}
+	}
+
+	switch r.DBType {
+	case "postgres":
+		return postgres.RunQuery(ctx, r.DB, req, r.ReadOnly)
+	default:
+		return nil, errors.New("unsupported database type")
+	}
+}
+
+func (r *Repository) StreamQuery(ctx context.Context, req models.QueryRequest, sink models.RowSink) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, req.Query)
+	defer finish()
+
+	if req.SessionID != "" {
+		conn, release, err := r.Sessions.Use(req.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		switch r.DBType {
+		case "postgres":
+			return postgres.StreamSessionQuery(ctx, conn, req, sink)
+		default:
+			return nil, errors.New("unsupported database type")
+		}
+	}
+
+	switch r.DBType {
+	case "postgres":
+		return postgres.StreamQuery(ctx, r.DB, req, r.ReadOnly, sink)
+	default:
+		return nil, errors.New("unsupported database type")
+	}
+}
+
+func (r *Repository) DescribeQuery(ctx context.Context, req models.QueryRequest) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, req.Query)
+	defer finish()
+
+	switch r.DBType {
+	case "postgres":
+		return postgres.DescribeQuery(ctx, r.DB, req)
+	default:
+		return nil, errors.New("unsupported database type")
+	}
 }
 
-func NewRepository(db *pgxpool.Pool, dbType string) *Repository {
-	return &Repository{DB: db, DBType: dbType}
+func (r *Repository) ExplainQuery(ctx context.Context, req models.ExplainRequest) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, req.Query)
+	defer finish()
+
+	switch r.DBType {
+	case "postgres":
+		return postgres.ExplainQuery(ctx, r.DB, req)
+	default:
+		return nil, errors.New("unsupported database type")
+	}
 }
 
-func (r *Repository) RunQuery(query string) (*models.ApiResponse, error) {
+func (r *Repository) Complete(ctx context.Context, sql string, cursor int) (*models.ApiResponse, error) {
 	switch r.DBType {
 	case "postgres":
-		return postgres.RunQuery(r.DB, query)
+		return postgres.Complete(ctx, r.DB, r.Catalog, sql, cursor)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
+
+func (r *Repository) GetRunningQueries() (*models.ApiResponse, error) {
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    r.Tracker.List(),
+	}, nil
+}
+
+func (r *Repository) CancelQuery(ctx context.Context, id string) (*models.ApiResponse, error) {
+	running, done, err := r.Tracker.Cancel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-done:
+		return &models.ApiResponse{
+			Status:  http.StatusOK,
+			Message: "cancelled",
+			Data:    running,
+		}, nil
+	case <-time.After(cancelGracePeriod):
+	}
+
+	if running.PID == 0 {
+		return nil, errors.New("query did not stop after being cancelled")
+	}
+
+	switch r.DBType {
+	case "postgres":
+		res, err := postgres.CancelBackend(ctx, r.DB, running.PID)
+		if err != nil {
+			return nil, err
+		}
+		res.Data = running
+		return res, nil
+	default:
+		return nil, errors.New("unsupported database type")
+	}
+}
+
+// OpenSession takes a connection out of the pool for a new session. Sessions
+// let scripts control transactions themselves, so they are not available in
+// read-only mode.
+func (r *Repository) OpenSession(ctx context.Context) (*models.ApiResponse, error) {
+	if r.ReadOnly {
+		return nil, models.ErrReadOnly
+	}
+
+	conn, err := r.DB.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := r.Sessions.Open(conn)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusCreated,
+		Message: "created",
+		Data:    session,
+	}, nil
+}
+
+func (r *Repository) GetSessions() (*models.ApiResponse, error) {
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    r.Sessions.List(),
+	}, nil
+}
+
+func (r *Repository) CloseSession(id string) (*models.ApiResponse, error) {
+	session, err := r.Sessions.Close(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "closed",
+		Data:    session,
+	}, nil
+}
@@ -1,15 +1,148 @@
 package query
 
-import "github.com/euandresimoes/visualdb-go.git/internal/models"
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/euandresimoes/visualdb-go.git/internal/modules/history"
+)
 
 type Service struct {
 	Repository *Repository
+	History    *history.Service
+}
+
+func NewService(repository *Repository, history *history.Service) *Service {
+	return &Service{Repository: repository, History: history}
+}
+
+func (s *Service) RunQuery(ctx context.Context, req models.QueryRequest) (*models.ApiResponse, error) {
+	startedAt := time.Now()
+	res, err := s.Repository.RunQuery(ctx, req)
+	s.recordHistory(req, startedAt, res, err)
+
+	return res, err
+}
+
+func (s *Service) StreamQuery(ctx context.Context, req models.QueryRequest, sink models.RowSink) (*models.ApiResponse, error) {
+	startedAt := time.Now()
+	res, err := s.Repository.StreamQuery(ctx, req, sink)
+	s.recordHistory(req, startedAt, res, err)
+
+	return res, err
+}
+
+func (s *Service) DescribeQuery(ctx context.Context, req models.QueryRequest) (*models.ApiResponse, error) {
+	return s.Repository.DescribeQuery(ctx, req)
+}
+
+func (s *Service) ExplainQuery(ctx context.Context, req models.ExplainRequest) (*models.ApiResponse, error) {
+	return s.Repository.ExplainQuery(ctx, req)
+}
+
+func (s *Service) Complete(ctx context.Context, sql string, cursor int) (*models.ApiResponse, error) {
+	return s.Repository.Complete(ctx, sql, cursor)
+}
+
+func (s *Service) GetRunningQueries() (*models.ApiResponse, error) {
+	return s.Repository.GetRunningQueries()
+}
+
+func (s *Service) CancelQuery(ctx context.Context, id string) (*models.ApiResponse, error) {
+	return s.Repository.CancelQuery(ctx, id)
 }
 
-func NewService(repository *Repository) *Service {
-	return &Service{Repository: repository}
+func (s *Service) OpenSession(ctx context.Context) (*models.ApiResponse, error) {
+	return s.Repository.OpenSession(ctx)
+}
+
+func (s *Service) GetSessions() (*models.ApiResponse, error) {
+	return s.Repository.GetSessions()
+}
+
+func (s *Service) CloseSession(id string) (*models.ApiResponse, error) {
+	return s.Repository.CloseSession(id)
+}
+
+func (s *Service) GetHistory(search string, page int, limit int) (*models.HistoryPage, error) {
+	return s.History.GetEntries(search, page, limit)
+}
+
+// RerunHistory runs the query of a history entry again, with the same
+// parameters. The new run is recorded as a new entry.
+func (s *Service) RerunHistory(ctx context.Context, id uint64, req models.QueryRequest) (*models.ApiResponse, error) {
+	entry, err := s.History.GetEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Query = entry.Query
+	req.Params = entry.Params
+
+	return s.RunQuery(ctx, req)
+}
+
+// recordHistory stores one history entry per statement that was run, or a
+// single entry when the query failed as a whole. Failing to record is
+// logged but never fails the query itself.
+func (s *Service) recordHistory(req models.QueryRequest, startedAt time.Time, res *models.ApiResponse, err error) {
+	base := models.HistoryEntry{
+		Query:     req.Query,
+		Params:    req.Params,
+		StartedAt: startedAt,
+		User:      req.ClientUser,
+		IP:        req.ClientIP,
+	}
+
+	var entries []models.HistoryEntry
+
+	switch data := dataOf(res).(type) {
+	case *models.ScriptResult:
+		for _, stmt := range data.Statements {
+			if stmt.Skipped {
+				continue
+			}
+
+			entry := base
+			entry.Query = stmt.Statement
+			entry.StartedAt = stmt.StartedAt
+			entry.DurationMs = stmt.DurationMs
+			entry.Error = stmt.Error
+			if stmt.QueryResult != nil {
+				entry.RowsAffected = stmt.RowsAffected
+				entry.RowsReturned = int64(len(stmt.Rows))
+			}
+			entries = append(entries, entry)
+		}
+
+	case *models.QueryResult:
+		entry := base
+		entry.DurationMs = float64(time.Since(startedAt).Microseconds()) / 1000
+		entry.RowsAffected = data.RowsAffected
+		if data.Columns != nil {
+			entry.RowsReturned = data.RowsAffected
+		}
+		entries = append(entries, entry)
+
+	default:
+		entry := base
+		entry.DurationMs = float64(time.Since(startedAt).Microseconds()) / 1000
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := s.History.AddEntries(entries); err != nil {
+		log.Printf("Error while recording query history: %v\n", err)
+	}
 }
 
-func (s *Service) RunQuery(query string) (*models.ApiResponse, error) {
-	return s.Repository.RunQuery(query)
+func dataOf(res *models.ApiResponse) any {
+	if res == nil {
+		return nil
+	}
+	return res.Data
 }
@@ -5,36 +5,66 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
 	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/limits"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
 	Service *Service
+	Limits  limits.Config
 }
 
-func NewHandler(service *Service) http.Handler {
-	h := &Handler{Service: service}
+// NewHandler mounts the rows routes. In read-only mode the routes that
+// modify data answer 403 instead.
+func NewHandler(service *Service, readOnly bool, limits limits.Config) http.Handler {
+	h := &Handler{Service: service, Limits: limits}
 	r := chi.NewRouter()
 
 	r.Get("/export", h.ExportRowsToCSV)
 	r.Get("/", h.GetRows)
-	r.Post("/", h.InsertRow)
-	r.Delete("/", h.DeleteRow)
-	r.Patch("/", h.UpdateRow)
+
+	if readOnly {
+		r.Post("/", h.ReadOnly)
+		r.Delete("/", h.ReadOnly)
+		r.Patch("/", h.ReadOnly)
+		r.Post("/changeset", h.ReadOnly)
+	} else {
+		r.Post("/", h.InsertRow)
+		r.Delete("/", h.DeleteRow)
+		r.Patch("/", h.UpdateRow)
+		r.Post("/changeset", h.ApplyChangeset)
+	}
 
 	return r
 }
 
+func (h *Handler) ReadOnly(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusForbidden,
+		Message: models.ErrReadOnly.Error(),
+	})
+}
+
 func (h *Handler) GetRows(w http.ResponseWriter, r *http.Request) {
 	var (
 		schema   = r.URL.Query().Get("schema")
 		table    = r.URL.Query().Get("table")
 		page, _  = strconv.Atoi(r.URL.Query().Get("page"))
 		limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+		stream   = r.URL.Query().Get("stream")
+		count    = r.URL.Query().Get("count")
+		paging   = r.URL.Query().Get("paging")
+		cursor   = r.URL.Query().Get("cursor")
+
+		timeoutMs, _ = strconv.Atoi(r.URL.Query().Get("timeout_ms"))
+		maxRows, _   = strconv.Atoi(r.URL.Query().Get("max_rows"))
 	)
 
 	if !httpx.Require(w, schema, "schema") {
@@ -43,31 +73,191 @@ func (h *Handler) GetRows(w http.ResponseWriter, r *http.Request) {
 	if !httpx.Require(w, table, "table") {
 		return
 	}
-	if !httpx.Require(w, page, "page") {
+
+	filter, ok := rowFilter(w, r)
+	if !ok {
 		return
 	}
-	if !httpx.Require(w, limit, "limit") {
+
+	sort, ok := rowSort(w, r)
+	if !ok {
 		return
 	}
 
-	rows, err := h.Service.GetRows(schema, table, page, limit)
-	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+	switch count {
+	case "":
+		count = models.CountNone
+	case models.CountExact, models.CountEstimate, models.CountNone:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+			Message: "count must be exact, estimate or none",
+		})
+		return
+	}
+
+	switch paging {
+	case "":
+		paging = models.PagingOffset
+	case models.PagingOffset, models.PagingKeyset:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: "paging must be offset or keyset",
 		})
 		return
 	}
 
+	q := models.RowsQuery{
+		Page:   page,
+		Limit:  limit,
+		Filter: filter,
+		Sort:   sort,
+		Count:  count,
+		Paging: paging,
+		Cursor: cursor,
+	}
+
+	queryLimits := models.QueryLimits{TimeoutMs: timeoutMs, MaxRows: maxRows}
+
+	if stream != "" {
+		h.streamRows(w, r, schema, table, q, h.Limits.ResolveStream(queryLimits), stream)
+		return
+	}
+
+	if paging == models.PagingOffset && !httpx.Require(w, page, "page") {
+		return
+	}
+	if !httpx.Require(w, limit, "limit") {
+		return
+	}
+
+	rows, err := h.Service.GetRows(r.Context(), schema, table, q, h.Limits.Resolve(queryLimits))
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	rows.QueryID = activity.ID(r.Context())
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(rows)
 }
 
+// rowFilter reads the optional filter query param, a JSON-encoded
+// models.RowFilter such as
+// {"and": [{"column": "age", "op": "gte", "value": 18}, ...]}.
+func rowFilter(w http.ResponseWriter, r *http.Request) (*models.RowFilter, bool) {
+	raw := r.URL.Query().Get("filter")
+	if raw == "" {
+		return nil, true
+	}
+
+	var filter models.RowFilter
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&filter); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("%v: %v", models.ErrInvalidFilter, err),
+		})
+		return nil, false
+	}
+
+	return &filter, true
+}
+
+// rowSort reads the optional sort query param, a comma-separated list of
+// column[:asc|desc[:nulls_first|nulls_last]] terms such as
+// sort=name:asc,created_at:desc:nulls_last.
+func rowSort(w http.ResponseWriter, r *http.Request) ([]models.RowSort, bool) {
+	raw := r.URL.Query().Get("sort")
+	if raw == "" {
+		return nil, true
+	}
+
+	var sorts []models.RowSort
+	for term := range strings.SplitSeq(raw, ",") {
+		parts := strings.Split(term, ":")
+		s := models.RowSort{Column: parts[0]}
+
+		valid := s.Column != "" && len(parts) <= 3
+		if valid && len(parts) > 1 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				s.Desc = true
+			default:
+				valid = false
+			}
+		}
+		if valid && len(parts) > 2 {
+			switch strings.ToLower(parts[2]) {
+			case "nulls_first":
+				s.Nulls = models.NullsFirst
+			case "nulls_last":
+				s.Nulls = models.NullsLast
+			default:
+				valid = false
+			}
+		}
+
+		if !valid {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("%v: %q, expected column[:asc|desc[:nulls_first|nulls_last]]", models.ErrInvalidSort, term),
+			})
+			return nil, false
+		}
+
+		sorts = append(sorts, s)
+	}
+
+	return sorts, true
+}
+
+// streamRows writes the rows of a table as they are read. Unlike GetRows,
+// page and limit are optional: without a limit the whole table is streamed.
+func (h *Handler) streamRows(w http.ResponseWriter, r *http.Request, schema string, table string, q models.RowsQuery, queryLimits models.QueryLimits, format string) {
+	if !httpx.ValidStreamFormat(format) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: "stream must be ndjson or sse",
+		})
+		return
+	}
+
+	stream := httpx.NewStreamWriter(w, format)
+	res, err := h.Service.StreamRows(r.Context(), schema, table, q, queryLimits, stream)
+	if err != nil && stream.Started() {
+		stream.Error(err)
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
+	stream.Done(res)
+}
+
+// InsertRow inserts the row in the body or, when the body is a list of
+// rows, all of them at once. The on_conflict query param (do_nothing or
+// do_update) turns the insert into an upsert on the unique constraint named
+// by conflict_constraint or the one on the comma-separated
+// conflict_columns.
 func (h *Handler) InsertRow(w http.ResponseWriter, r *http.Request) {
 	var (
-		schema = r.URL.Query().Get("schema")
-		table  = r.URL.Query().Get("table")
+		schema     = r.URL.Query().Get("schema")
+		table      = r.URL.Query().Get("table")
+		onConflict = r.URL.Query().Get("on_conflict")
+		constraint = r.URL.Query().Get("conflict_constraint")
+		columns    = r.URL.Query().Get("conflict_columns")
 	)
 
 	if !httpx.Require(w, schema, "schema") {
@@ -77,28 +267,96 @@ func (h *Handler) InsertRow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var bodyData map[string]any
-	if err := json.NewDecoder(r.Body).Decode(&bodyData); err != nil {
+	switch onConflict {
+	case "", models.OnConflictDoNothing, models.OnConflictDoUpdate:
+	default:
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
 			Status:  http.StatusBadRequest,
-			Message: err.Error(),
+			Message: "on_conflict must be do_nothing or do_update",
 		})
 		return
 	}
 
-	row, err := h.Service.InsertRow(schema, table, bodyData)
-	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+	opts := models.InsertOptions{OnConflict: onConflict, ConflictConstraint: constraint}
+	if columns != "" {
+		opts.ConflictColumns = strings.Split(columns, ",")
+	}
+
+	var bodyData any
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
+			Status:  http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	var (
+		res *models.ApiResponse
+		err error
+	)
+	switch body := bodyData.(type) {
+	case map[string]any:
+		res, err = h.Service.InsertRow(r.Context(), schema, table, body, opts, h.timeoutMs(r))
+
+	case []any:
+		rows, ok := bodyRows(w, body)
+		if !ok {
+			return
+		}
+		res, err = h.Service.InsertRows(r.Context(), schema, table, rows, opts, h.timeoutMs(r))
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: "the body must be a row or a list of rows",
+		})
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(row)
+	json.NewEncoder(w).Encode(res)
+}
+
+// timeoutMs resolves the optional timeout_ms query param of the routes
+// writing rows.
+func (h *Handler) timeoutMs(r *http.Request) int {
+	timeoutMs, _ := strconv.Atoi(r.URL.Query().Get("timeout_ms"))
+	return h.Limits.Resolve(models.QueryLimits{TimeoutMs: timeoutMs}).TimeoutMs
+}
+
+// bodyRows checks that the list of rows of a bulk insert holds at least one
+// row and nothing but rows.
+func bodyRows(w http.ResponseWriter, list []any) ([]map[string]any, bool) {
+	if !httpx.Require(w, len(list), "rows") {
+		return nil, false
+	}
+
+	rows := make([]map[string]any, len(list))
+	for i, item := range list {
+		row, ok := item.(map[string]any)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("row %d is not an object", i),
+			})
+			return nil, false
+		}
+		rows[i] = row
+	}
+
+	return rows, true
 }
 
 func (h *Handler) DeleteRow(w http.ResponseWriter, r *http.Request) {
@@ -114,12 +372,16 @@ func (h *Handler) DeleteRow(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type Body struct {
-		PKColumn string `json:"pk_column"`
-		PKValue  any    `json:"pk_value"`
+		Key      map[string]any `json:"key"`
+		PKColumn string         `json:"pk_column"`
+		PKValue  any            `json:"pk_value"`
+		Version  string         `json:"version"`
 	}
 
 	var bodyData Body
-	if err := json.NewDecoder(r.Body).Decode(&bodyData); err != nil {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
 			Status:  http.StatusBadRequest,
@@ -128,16 +390,21 @@ func (h *Handler) DeleteRow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row, err := h.Service.DeleteRow(schema, table, bodyData.PKColumn, bodyData.PKValue)
+	key, ok := rowKey(w, bodyData.Key, bodyData.PKColumn, bodyData.PKValue)
+	if !ok {
+		return
+	}
+	if !httpx.Require(w, bodyData.Version, "version") {
+		return
+	}
+
+	row, err := h.Service.DeleteRow(r.Context(), schema, table, key, bodyData.Version, h.timeoutMs(r))
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
-		})
+		httpx.WriteError(w, err)
 		return
 	}
 
+	row.QueryID = activity.ID(r.Context())
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(row)
 }
@@ -155,13 +422,17 @@ func (h *Handler) UpdateRow(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type Body struct {
+		Key      map[string]any `json:"key"`
 		PKColumn string         `json:"pk_column"`
 		PKValue  any            `json:"pk_value"`
+		Version  string         `json:"version"`
 		Data     map[string]any `json:"data"`
 	}
 
 	var bodyData Body
-	if err := json.NewDecoder(r.Body).Decode(&bodyData); err != nil {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
 			Status:  http.StatusBadRequest,
@@ -170,24 +441,114 @@ func (h *Handler) UpdateRow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row, err := h.Service.UpdateRow(schema, table, bodyData.PKColumn, bodyData.PKValue, bodyData.Data)
+	key, ok := rowKey(w, bodyData.Key, bodyData.PKColumn, bodyData.PKValue)
+	if !ok {
+		return
+	}
+	if !httpx.Require(w, bodyData.Version, "version") {
+		return
+	}
+
+	row, err := h.Service.UpdateRow(r.Context(), schema, table, key, bodyData.Version, bodyData.Data, h.timeoutMs(r))
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+		httpx.WriteError(w, err)
+		return
+	}
+
+	row.QueryID = activity.ID(r.Context())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(row)
+}
+
+// rowKey returns the key identifying the row to change: an object holding
+// the value of every key column, or the older single pk_column/pk_value
+// pair.
+func rowKey(w http.ResponseWriter, key map[string]any, pkColumn string, pkValue any) (map[string]any, bool) {
+	if key == nil && pkColumn != "" {
+		key = map[string]any{pkColumn: pkValue}
+	}
+
+	if len(key) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
+			Status:  http.StatusBadRequest,
+			Message: "key is required",
+		})
+		return nil, false
+	}
+
+	return key, true
+}
+
+// ApplyChangeset applies a list of inserts, updates and deletes, on one or
+// more tables, in a single transaction. Updates and deletes identify their
+// row with a key and version, like single edits. When a change fails the whole
+// changeset is rolled back and the response tells which change failed.
+func (h *Handler) ApplyChangeset(w http.ResponseWriter, r *http.Request) {
+	var bodyData models.ChangesetRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	if !httpx.Require(w, len(bodyData.Changes), "changes") {
+		return
+	}
+
+	for i, change := range bodyData.Changes {
+		var problem string
+		switch {
+		case change.Op != models.ChangeInsert && change.Op != models.ChangeUpdate && change.Op != models.ChangeDelete:
+			problem = "op must be insert, update or delete"
+		case change.Schema == "":
+			problem = "schema is required"
+		case change.Table == "":
+			problem = "table is required"
+		case change.Op != models.ChangeInsert && len(change.Key) == 0:
+			problem = "key is required"
+		case change.Op != models.ChangeInsert && change.Version == "":
+			problem = "version is required"
+		case change.Op == models.ChangeUpdate && len(change.Data) == 0:
+			problem = "data is required"
+		}
+
+		if problem != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ApiResponse{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("change %d: %s", i, problem),
+			})
+			return
+		}
+	}
+
+	res, err := h.Service.ApplyChangeset(r.Context(), bodyData.Changes, h.timeoutMs(r))
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	res.QueryID = activity.ID(r.Context())
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(row)
+	json.NewEncoder(w).Encode(res)
 }
 
+// ExportRowsToCSV downloads the table as CSV. An export carries the whole
+// table, so like streamed rows it gets no default timeout or row limit,
+// only the timeout_ms and max_rows it asks for, within the ceilings.
 func (h *Handler) ExportRowsToCSV(w http.ResponseWriter, r *http.Request) {
 	var (
 		schema = r.URL.Query().Get("schema")
 		table  = r.URL.Query().Get("table")
+
+		timeoutMs, _ = strconv.Atoi(r.URL.Query().Get("timeout_ms"))
+		maxRows, _   = strconv.Atoi(r.URL.Query().Get("max_rows"))
 	)
 	if !httpx.Require(w, schema, "schema") {
 		return
@@ -218,13 +579,11 @@ func (h *Handler) ExportRowsToCSV(w http.ResponseWriter, r *http.Request) {
 		fmt.Sprintf(`attachment; filename="%s"`, date),
 	)
 
-	err := h.Service.ExportRowsToCSV(ctx, schema, table, w)
+	queryLimits := h.Limits.ResolveStream(models.QueryLimits{TimeoutMs: timeoutMs, MaxRows: maxRows})
+
+	err := h.Service.ExportRowsToCSV(ctx, schema, table, queryLimits, w)
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
-		})
+		httpx.WriteError(w, err)
 		return
 	}
 }
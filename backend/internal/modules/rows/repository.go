@@ -2,113 +2,119 @@ package rows
 
 import (
 	"context"
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/activity"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repository struct {
-	DB     *pgxpool.Pool
-	DBType string
+	DB      *pgxpool.Pool
+	DBType  string
+	Tracker *activity.Tracker
 }
 
-func NewRepository(db *pgxpool.Pool, dbType string) *Repository {
-	return &Repository{DB: db, DBType: dbType}
+func NewRepository(db *pgxpool.Pool, dbType string, tracker *activity.Tracker) *Repository {
+	return &Repository{DB: db, DBType: dbType, Tracker: tracker}
 }
 
-func (r *Repository) GetRows(schema string, table string, page int, limit int) (*models.ApiResponse, error) {
+func (r *Repository) GetRows(ctx context.Context, schema string, table string, q models.RowsQuery, limits models.QueryLimits) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, "SELECT * FROM "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
+
 	switch r.DBType {
 	case "postgres":
-		return postgres.GetRows(r.DB, schema, table, page, limit)
+		return postgres.GetRows(ctx, r.DB, schema, table, q, limits)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
 
-func (r *Repository) InsertRow(schema string, table string, row map[string]any) (*models.ApiResponse, error) {
+func (r *Repository) StreamRows(ctx context.Context, schema string, table string, q models.RowsQuery, limits models.QueryLimits, sink models.RowSink) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, "SELECT * FROM "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
+
 	switch r.DBType {
 	case "postgres":
-		return postgres.InsertRow(r.DB, schema, table, row)
+		return postgres.StreamRows(ctx, r.DB, schema, table, q, limits, sink)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
 
-func (r *Repository) DeleteRow(schema string, table string, pkColumn string, pkValue any) (*models.ApiResponse, error) {
+func (r *Repository) InsertRow(ctx context.Context, schema string, table string, row map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, "INSERT INTO "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
+
 	switch r.DBType {
 	case "postgres":
-		return postgres.DeleteRow(r.DB, schema, table, pkColumn, pkValue)
+		return postgres.InsertRow(ctx, r.DB, schema, table, row, opts, timeoutMs)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
 
-func (r *Repository) UpdateRow(schema string, table string, pkColumn string, pkValue any, row map[string]any) (*models.ApiResponse, error) {
+func (r *Repository) InsertRows(ctx context.Context, schema string, table string, rows []map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, fmt.Sprintf("INSERT INTO %s (%d rows)", pgx.Identifier{schema, table}.Sanitize(), len(rows)))
+	defer finish()
+
 	switch r.DBType {
 	case "postgres":
-		return postgres.UpdateRow(r.DB, schema, table, pkColumn, pkValue, row)
+		return postgres.InsertRows(ctx, r.DB, schema, table, rows, opts, timeoutMs)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
 
-func (r *Repository) ExportRowsToCSV(ctx context.Context, schema string, table string, w io.Writer) error {
-	query := fmt.Sprintf(`
-		SELECT * FROM "%s"."%s"
-	`, schema, table)
-
-	rows, err := r.DB.Query(
-		ctx,
-		query,
-	)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	csvWriter := csv.NewWriter(w)
-	defer csvWriter.Flush()
+func (r *Repository) DeleteRow(ctx context.Context, schema string, table string, key map[string]any, version string, timeoutMs int) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, "DELETE FROM "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
 
-	fieldDescriptions := rows.FieldDescriptions()
-	headers := make([]string, len(fieldDescriptions))
-	for i, fd := range fieldDescriptions {
-		headers[i] = string(fd.Name)
+	switch r.DBType {
+	case "postgres":
+		return postgres.DeleteRow(ctx, r.DB, schema, table, key, version, timeoutMs)
+	default:
+		return nil, errors.New("unsupported database type")
 	}
+}
 
-	if err := csvWriter.Write(headers); err != nil {
-		return err
+func (r *Repository) UpdateRow(ctx context.Context, schema string, table string, key map[string]any, version string, row map[string]any, timeoutMs int) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, "UPDATE "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
+
+	switch r.DBType {
+	case "postgres":
+		return postgres.UpdateRow(ctx, r.DB, schema, table, key, version, row, timeoutMs)
+	default:
+		return nil, errors.New("unsupported database type")
 	}
+}
 
-	values := make([]any, len(headers))
-	valuesPtrs := make([]any, len(headers))
+func (r *Repository) ApplyChangeset(ctx context.Context, changes []models.RowChange, timeoutMs int) (*models.ApiResponse, error) {
+	ctx, finish := r.Tracker.Start(ctx, fmt.Sprintf("changeset of %d changes", len(changes)))
+	defer finish()
 
-	for i := range values {
-		valuesPtrs[i] = &values[i]
+	switch r.DBType {
+	case "postgres":
+		return postgres.ApplyChangeset(ctx, r.DB, changes, timeoutMs)
+	default:
+		return nil, errors.New("unsupported database type")
 	}
+}
 
-	for rows.Next() {
-		if err := rows.Scan(valuesPtrs...); err != nil {
-			return err
-		}
-
-		record := make([]string, len(values))
-		for i, v := range values {
-			if v == nil {
-				record[i] = ""
-			} else {
-				record[i] = fmt.Sprint(v)
-			}
-		}
-
-		if err := csvWriter.Write(record); err != nil {
-			return err
-		}
-	}
+func (r *Repository) ExportRowsToCSV(ctx context.Context, schema string, table string, limits models.QueryLimits, w io.Writer) error {
+	ctx, finish := r.Tracker.Start(ctx, "SELECT * FROM "+pgx.Identifier{schema, table}.Sanitize())
+	defer finish()
 
-	return rows.Err()
+	switch r.DBType {
+	case "postgres":
+		return postgres.ExportRows(ctx, r.DB, schema, table, limits, w)
+	default:
+		return errors.New("unsupported database type")
+	}
 }
@@ -15,22 +15,34 @@ func NewService(repository *Repository) *Service {
 	return &Service{Repository: repository}
 }
 
-func (s *Service) GetRows(schema string, table string, page int, limit int) (*models.ApiResponse, error) {
-	return s.Repository.GetRows(schema, table, page, limit)
+func (s *Service) GetRows(ctx context.Context, schema string, table string, q models.RowsQuery, limits models.QueryLimits) (*models.ApiResponse, error) {
+	return s.Repository.GetRows(ctx, schema, table, q, limits)
 }
 
-func (s *Service) InsertRow(schema string, table string, row map[string]any) (*models.ApiResponse, error) {
-	return s.Repository.InsertRow(schema, table, row)
+func (s *Service) StreamRows(ctx context.Context, schema string, table string, q models.RowsQuery, limits models.QueryLimits, sink models.RowSink) (*models.ApiResponse, error) {
+	return s.Repository.StreamRows(ctx, schema, table, q, limits, sink)
 }
 
-func (s *Service) DeleteRow(schema string, table string, pkColumn string, pkValue any) (*models.ApiResponse, error) {
-	return s.Repository.DeleteRow(schema, table, pkColumn, pkValue)
+func (s *Service) InsertRow(ctx context.Context, schema string, table string, row map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	return s.Repository.InsertRow(ctx, schema, table, row, opts, timeoutMs)
 }
 
-func (s *Service) UpdateRow(schema string, table string, pkColumn string, pkValue any, row map[string]any) (*models.ApiResponse, error) {
-	return s.Repository.UpdateRow(schema, table, pkColumn, pkValue, row)
+func (s *Service) InsertRows(ctx context.Context, schema string, table string, rows []map[string]any, opts models.InsertOptions, timeoutMs int) (*models.ApiResponse, error) {
+	return s.Repository.InsertRows(ctx, schema, table, rows, opts, timeoutMs)
 }
 
-func (s *Service) ExportRowsToCSV(ctx context.Context, schema string, table string, w io.Writer) error {
-	return s.Repository.ExportRowsToCSV(ctx, schema, table, w)
+func (s *Service) DeleteRow(ctx context.Context, schema string, table string, key map[string]any, version string, timeoutMs int) (*models.ApiResponse, error) {
+	return s.Repository.DeleteRow(ctx, schema, table, key, version, timeoutMs)
+}
+
+func (s *Service) UpdateRow(ctx context.Context, schema string, table string, key map[string]any, version string, row map[string]any, timeoutMs int) (*models.ApiResponse, error) {
+	return s.Repository.UpdateRow(ctx, schema, table, key, version, row, timeoutMs)
+}
+
+func (s *Service) ApplyChangeset(ctx context.Context, changes []models.RowChange, timeoutMs int) (*models.ApiResponse, error) {
+	return s.Repository.ApplyChangeset(ctx, changes, timeoutMs)
+}
+
+func (s *Service) ExportRowsToCSV(ctx context.Context, schema string, table string, limits models.QueryLimits, w io.Writer) error {
+	return s.Repository.ExportRowsToCSV(ctx, schema, table, limits, w)
 }
@@ -0,0 +1,191 @@
+package savedqueries
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Service *Service
+}
+
+func NewHandler(service *Service) http.Handler {
+	h := &Handler{Service: service}
+	r := chi.NewRouter()
+
+	r.Get("/", h.GetSavedQueries)
+	r.Post("/", h.CreateSavedQuery)
+	r.Get("/{id}", h.GetSavedQuery)
+	r.Put("/{id}", h.UpdateSavedQuery)
+	r.Delete("/{id}", h.DeleteSavedQuery)
+
+	return r
+}
+
+func (h *Handler) GetSavedQueries(w http.ResponseWriter, r *http.Request) {
+	var (
+		folder = r.URL.Query().Get("folder")
+		tag    = r.URL.Query().Get("tag")
+		search = r.URL.Query().Get("search")
+	)
+
+	queries, err := h.Service.GetSavedQueries(folder, tag, search)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    queries,
+	})
+}
+
+func (h *Handler) GetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := savedQueryID(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := h.Service.GetSavedQuery(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    query,
+	})
+}
+
+func (h *Handler) CreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	bodyData, ok := decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := h.Service.CreateSavedQuery(bodyData)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusCreated,
+		Message: "created",
+		Data:    query,
+	})
+}
+
+func (h *Handler) UpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := savedQueryID(w, r)
+	if !ok {
+		return
+	}
+
+	bodyData, ok := decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := h.Service.UpdateSavedQuery(id, bodyData)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    query,
+	})
+}
+
+func (h *Handler) DeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := savedQueryID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteSavedQuery(id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+	})
+}
+
+func savedQueryID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: "invalid saved query id",
+		})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// decodeSavedQuery reads a saved query from the request body. Numbers are
+// kept as json.Number so parameter defaults are stored as they were sent.
+func decodeSavedQuery(w http.ResponseWriter, r *http.Request) (models.SavedQuery, bool) {
+	var bodyData models.SavedQuery
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyData); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ApiResponse{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return bodyData, false
+	}
+
+	if !httpx.Require(w, bodyData.Name, "name") {
+		return bodyData, false
+	}
+	if !httpx.Require(w, bodyData.Query, "query") {
+		return bodyData, false
+	}
+
+	for _, param := range bodyData.Params {
+		if !httpx.Require(w, param.Name, "params.name") {
+			return bodyData, false
+		}
+	}
+
+	return bodyData, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if !errors.Is(err, ErrNotFound) {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(models.ApiResponse{
+		Status:  http.StatusNotFound,
+		Message: err.Error(),
+	})
+}
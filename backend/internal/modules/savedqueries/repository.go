@@ -0,0 +1,189 @@
+package savedqueries
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"go.etcd.io/bbolt"
+)
+
+var ErrNotFound = errors.New("saved query not found")
+
+var bucket = []byte("saved_queries")
+
+type Repository struct {
+	DB *bbolt.DB
+}
+
+func NewRepository(db *bbolt.DB) (*Repository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{DB: db}, nil
+}
+
+// GetSavedQueries lists the saved queries in folder (and its subfolders),
+// carrying tag and containing search in their name, description or SQL.
+// Empty filters match everything.
+func (r *Repository) GetSavedQueries(folder string, tag string, search string) ([]models.SavedQuery, error) {
+	queries := []models.SavedQuery{}
+	search = strings.ToLower(search)
+
+	err := r.DB.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, v []byte) error {
+			query, err := decode(v)
+			if err != nil {
+				return err
+			}
+
+			if folder != "" && query.Folder != folder && !strings.HasPrefix(query.Folder, folder+"/") {
+				return nil
+			}
+			if tag != "" && !slices.Contains(query.Tags, tag) {
+				return nil
+			}
+			if search != "" &&
+				!strings.Contains(strings.ToLower(query.Name), search) &&
+				!strings.Contains(strings.ToLower(query.Description), search) &&
+				!strings.Contains(strings.ToLower(query.Query), search) {
+				return nil
+			}
+
+			queries = append(queries, *query)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(queries, func(a, b models.SavedQuery) int {
+		if c := strings.Compare(a.Folder, b.Folder); c != 0 {
+			return c
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return queries, nil
+}
+
+func (r *Repository) GetSavedQuery(id uint64) (*models.SavedQuery, error) {
+	var query *models.SavedQuery
+
+	err := r.DB.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		query, err = decode(v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func (r *Repository) CreateSavedQuery(query models.SavedQuery) (*models.SavedQuery, error) {
+	err := r.DB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		query.ID = id
+		query.CreatedAt = time.Now()
+		query.UpdatedAt = query.CreatedAt
+
+		return put(b, &query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &query, nil
+}
+
+func (r *Repository) UpdateSavedQuery(id uint64, query models.SavedQuery) (*models.SavedQuery, error) {
+	err := r.DB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		v := b.Get(key(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		current, err := decode(v)
+		if err != nil {
+			return err
+		}
+
+		query.ID = id
+		query.CreatedAt = current.CreatedAt
+		query.UpdatedAt = time.Now()
+
+		return put(b, &query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &query, nil
+}
+
+func (r *Repository) DeleteSavedQuery(id uint64) error {
+	return r.DB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		if b.Get(key(id)) == nil {
+			return ErrNotFound
+		}
+
+		return b.Delete(key(id))
+	})
+}
+
+func put(b *bbolt.Bucket, query *models.SavedQuery) error {
+	value, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key(query.ID), value)
+}
+
+func key(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// decode keeps numbers as json.Number so parameter defaults survive the
+// round trip untouched.
+func decode(v []byte) (*models.SavedQuery, error) {
+	var query models.SavedQuery
+
+	decoder := json.NewDecoder(bytes.NewReader(v))
+	decoder.UseNumber()
+	if err := decoder.Decode(&query); err != nil {
+		return nil, err
+	}
+
+	return &query, nil
+}
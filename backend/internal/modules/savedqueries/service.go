@@ -0,0 +1,63 @@
+package savedqueries
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
+
+type Service struct {
+	Repository *Repository
+}
+
+func NewService(repository *Repository) *Service {
+	return &Service{Repository: repository}
+}
+
+// GetSavedQueries lists the saved queries matching the filters. The folder
+// is trimmed as when saving, so "/reports/" finds the queries in "reports".
+func (s *Service) GetSavedQueries(folder string, tag string, search string) ([]models.SavedQuery, error) {
+	return s.Repository.GetSavedQueries(normalizeFolder(folder), strings.TrimSpace(tag), search)
+}
+
+func (s *Service) GetSavedQuery(id uint64) (*models.SavedQuery, error) {
+	return s.Repository.GetSavedQuery(id)
+}
+
+func (s *Service) CreateSavedQuery(query models.SavedQuery) (*models.SavedQuery, error) {
+	return s.Repository.CreateSavedQuery(normalize(query))
+}
+
+func (s *Service) UpdateSavedQuery(id uint64, query models.SavedQuery) (*models.SavedQuery, error) {
+	return s.Repository.UpdateSavedQuery(id, normalize(query))
+}
+
+func (s *Service) DeleteSavedQuery(id uint64) error {
+	return s.Repository.DeleteSavedQuery(id)
+}
+
+// normalize trims folder slashes, drops blank and repeated tags and never
+// stores null lists.
+func normalize(query models.SavedQuery) models.SavedQuery {
+	query.Folder = normalizeFolder(query.Folder)
+
+	tags := []string{}
+	for _, tag := range query.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	query.Tags = tags
+	if query.Params == nil {
+		query.Params = []models.SavedQueryParam{}
+	}
+
+	return query
+}
+
+// normalizeFolder drops the leading and trailing slashes of a folder.
+func normalizeFolder(folder string) string {
+	return strings.Trim(folder, "/")
+}
@@ -0,0 +1,70 @@
+package savedqueries
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"go.etcd.io/bbolt"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repository, err := NewRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repository)
+}
+
+func TestGetSavedQueriesFolder(t *testing.T) {
+	s := newTestService(t)
+	for _, q := range []models.SavedQuery{
+		{Name: "daily", Folder: "/reports/", Query: "SELECT 1"},
+		{Name: "weekly", Folder: "reports/weekly", Query: "SELECT 2"},
+		{Name: "other", Folder: "reporting", Query: "SELECT 3"},
+		{Name: "root", Query: "SELECT 4"},
+	} {
+		if _, err := s.CreateSavedQuery(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		folder string
+		want   []string
+	}{
+		{folder: "", want: []string{"root", "other", "daily", "weekly"}},
+		{folder: "reports", want: []string{"daily", "weekly"}},
+		{folder: "/reports/", want: []string{"daily", "weekly"}},
+		{folder: "reports/", want: []string{"daily", "weekly"}},
+		{folder: "/reports/weekly", want: []string{"weekly"}},
+		{folder: "/", want: []string{"root", "other", "daily", "weekly"}},
+		{folder: "nope", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			queries, err := s.GetSavedQueries(tt.folder, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, q := range queries {
+				names = append(names, q.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("GetSavedQueries(%q) = %q, want %q", tt.folder, names, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/euandresimoes/visualdb-go.git/internal/models"
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -22,13 +22,9 @@ func NewHandler(service *Service) http.Handler {
 }
 
 func (h *Handler) GetSchemas(w http.ResponseWriter, r *http.Request) {
-	schemas, err := h.Service.GetSchemas()
+	schemas, err := h.Service.GetSchemas(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
-		})
+		httpx.WriteError(w, err)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package schemas
 
 import (
+	"context"
 	"errors"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
@@ -17,10 +18,10 @@ func NewRepository(db *pgxpool.Pool, dbType string) *Repository {
 	return &Repository{DB: db, DBType: dbType}
 }
 
-func (r *Repository) GetSchemas() (*models.ApiResponse, error) {
+func (r *Repository) GetSchemas(ctx context.Context) (*models.ApiResponse, error) {
 	switch r.DBType {
 	case "postgres":
-		return postgres.GetSchemas(r.DB)
+		return postgres.GetSchemas(ctx, r.DB)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
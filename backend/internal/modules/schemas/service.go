@@ -1,6 +1,10 @@
 package schemas
 
-import "github.com/euandresimoes/visualdb-go.git/internal/models"
+import (
+	"context"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
 
 type Service struct {
 	repository *Repository
@@ -10,6 +14,6 @@ func NewService(repository *Repository) *Service {
 	return &Service{repository: repository}
 }
 
-func (s *Service) GetSchemas() (*models.ApiResponse, error) {
-	return s.repository.GetSchemas()
+func (s *Service) GetSchemas(ctx context.Context) (*models.ApiResponse, error) {
+	return s.repository.GetSchemas(ctx)
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/euandresimoes/visualdb-go.git/internal/infra/httpx"
 	"github.com/euandresimoes/visualdb-go.git/internal/models"
 	"github.com/go-chi/chi/v5"
 )
@@ -32,13 +33,9 @@ func (h *Handler) GetTables(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tables, err := h.Service.GetTables(schema)
+	tables, err := h.Service.GetTables(r.Context(), schema)
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(models.ApiResponse{
-			Status:  http.StatusConflict,
-			Message: err.Error(),
-		})
+		httpx.WriteError(w, err)
 		return
 	}
 
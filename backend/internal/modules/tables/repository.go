@@ -1,6 +1,7 @@
 package tables
 
 import (
+	"context"
 	"errors"
 
 	"github.com/euandresimoes/visualdb-go.git/internal/drivers/postgres"
@@ -17,10 +18,10 @@ func NewRepository(db *pgxpool.Pool, dbType string) *Repository {
 	return &Repository{DB: db, DBType: dbType}
 }
 
-func (r *Repository) GetTables(schema string) (*models.ApiResponse, error) {
+func (r *Repository) GetTables(ctx context.Context, schema string) (*models.ApiResponse, error) {
 	switch r.DBType {
 	case "postgres":
-		return postgres.GetTables(r.DB, schema)
+		return postgres.GetTables(ctx, r.DB, schema)
 	default:
 		return nil, errors.New("unsupported database type")
 	}
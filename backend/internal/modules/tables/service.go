@@ -1,6 +1,10 @@
 package tables
 
-import "github.com/euandresimoes/visualdb-go.git/internal/models"
+import (
+	"context"
+
+	"github.com/euandresimoes/visualdb-go.git/internal/models"
+)
 
 type Service struct {
 	repository *Repository
@@ -10,6 +14,6 @@ func NewService(repository *Repository) *Service {
 	return &Service{repository: repository}
 }
 
-func (s *Service) GetTables(schema string) (*models.ApiResponse, error) {
-	return s.repository.GetTables(schema)
+func (s *Service) GetTables(ctx context.Context, schema string) (*models.ApiResponse, error) {
+	return s.repository.GetTables(ctx, schema)
 }
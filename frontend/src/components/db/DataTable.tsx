@@ -66,6 +66,21 @@ const getInputType = (dataType: string): string => {
   return "text";
 };
 
+// Form values are text: json columns take the JSON typed in. Other values,
+// bytea read as \x hex included, are sent as they are.
+const toParam = (value: unknown, dataType: string): unknown => {
+  if (typeof value !== "string") return value;
+  const lower = dataType.toLowerCase();
+  if (lower === "json" || lower === "jsonb") {
+    try {
+      return JSON.parse(value);
+    } catch {
+      return value;
+    }
+  }
+  return value;
+};
+
 const formatDateForInput = (value: unknown, dataType: string): string => {
   if (!value) return "";
   const date = new Date(value as string);
@@ -98,7 +113,7 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
   const [deleteRow, setDeleteRow] = useState<Record<string, unknown> | null>(
     null
   );
-  const [selectedRows, setSelectedRows] = useState<Set<unknown>>(new Set());
+  const [selectedRows, setSelectedRows] = useState<Set<string>>(new Set());
   const [deletingSelected, setDeletingSelected] = useState(false);
   const [expandedRow, setExpandedRow] = useState<number | null>(null);
   const { toast } = useToast();
@@ -152,6 +167,33 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
 
   const getPrimaryKey = () => columns.find((c) => c.is_primary_key);
 
+  // The key identifying a row: the values of its row key columns, which may
+  // be several, or its ctid when the table has no row key.
+  const getRowKey = (row: Record<string, unknown>) => {
+    const keyColumns = columns.filter((c) => c.is_row_key);
+    if (keyColumns.length === 0) return { ctid: row.ctid };
+    return Object.fromEntries(
+      keyColumns.map((c) => [c.column_name, row[c.column_name]])
+    );
+  };
+
+  const getRowId = (row: Record<string, unknown>) =>
+    JSON.stringify(getRowKey(row));
+
+  // Rows of views and other relations without row versions come without
+  // xmin and cannot be edited or deleted.
+  const isEditable = (row: Record<string, unknown>) => row.xmin !== undefined;
+  const editableRows = rows.filter(isEditable);
+
+  const toParams = (row: Record<string, unknown>) => {
+    const params: Record<string, unknown> = {};
+    for (const [name, value] of Object.entries(row)) {
+      const col = columns.find((c) => c.column_name === name);
+      params[name] = col ? toParam(value, col.data_type) : value;
+    }
+    return params;
+  };
+
   const handleSave = async () => {
     if (!editRow) return;
     const pk = getPrimaryKey();
@@ -161,13 +203,21 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
         if (pk?.column_default?.includes("nextval")) {
           delete dataToSend[pk.column_name];
         }
-        await api.createRow(schema, table, dataToSend);
+        await api.createRow(schema, table, toParams(dataToSend));
         toast({ title: "Success", description: "Row created successfully" });
-      } else if (pk) {
-        const pkValue = editRow[pk.column_name];
+      } else {
+        const key = getRowKey(editRow);
         const dataToSend = { ...editRow };
-        delete dataToSend[pk.column_name];
-        await api.updateRow(schema, table, pk.column_name, pkValue, dataToSend);
+        Object.keys(key).forEach((name) => delete dataToSend[name]);
+        delete dataToSend.xmin;
+        delete dataToSend.ctid;
+        await api.updateRow(
+          schema,
+          table,
+          key,
+          String(editRow.xmin),
+          toParams(dataToSend)
+        );
         toast({ title: "Success", description: "Row updated successfully" });
       }
       setEditRow(null);
@@ -209,15 +259,13 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
 
   const handleDelete = async () => {
     if (!deleteRow) return;
-    const pk = getPrimaryKey();
-    if (!pk) return;
 
     try {
       await api.deleteRow(
         schema,
         table,
-        pk.column_name,
-        deleteRow[pk.column_name]
+        getRowKey(deleteRow),
+        String(deleteRow.xmin)
       );
       toast({ title: "Success", description: "Row deleted successfully" });
       setDeleteRow(null);
@@ -232,14 +280,15 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
   };
 
   const handleDeleteSelected = async () => {
-    const pk = getPrimaryKey();
-    if (!pk || selectedRows.size === 0) return;
+    if (selectedRows.size === 0) return;
 
     setDeletingSelected(true);
     try {
-      const deletePromises = Array.from(selectedRows).map((pkValue) =>
-        api.deleteRow(schema, table, pk.column_name, pkValue)
-      );
+      const deletePromises = rows
+        .filter((row) => selectedRows.has(getRowId(row)))
+        .map((row) =>
+          api.deleteRow(schema, table, getRowKey(row), String(row.xmin))
+        );
       await Promise.all(deletePromises);
       toast({
         title: "Success",
@@ -258,13 +307,13 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
     }
   };
 
-  const toggleSelectRow = (pkValue: unknown, e: React.MouseEvent) => {
+  const toggleSelectRow = (rowId: string, e: React.MouseEvent) => {
     e.stopPropagation(); // Previne que o clique no checkbox expanda/retraia a linha
     const newSelected = new Set(selectedRows);
-    if (newSelected.has(pkValue)) {
-      newSelected.delete(pkValue);
+    if (newSelected.has(rowId)) {
+      newSelected.delete(rowId);
     } else {
-      newSelected.add(pkValue);
+      newSelected.add(rowId);
     }
     setSelectedRows(newSelected);
   };
@@ -274,13 +323,10 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
   };
 
   const toggleSelectAll = () => {
-    const pk = getPrimaryKey();
-    if (!pk) return;
-
-    if (selectedRows.size === rows.length) {
+    if (selectedRows.size === editableRows.length) {
       setSelectedRows(new Set());
     } else {
-      setSelectedRows(new Set(rows.map((row) => row[pk.column_name])));
+      setSelectedRows(new Set(editableRows.map(getRowId)));
     }
   };
 
@@ -364,8 +410,10 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
                 <TableHead className="w-10 text-center">
                   <Checkbox
                     checked={
-                      rows.length > 0 && selectedRows.size === rows.length
+                      editableRows.length > 0 &&
+                      selectedRows.size === editableRows.length
                     }
+                    disabled={editableRows.length === 0}
                     onCheckedChange={toggleSelectAll}
                     className="border-primary data-[state=checked]:bg-primary"
                   />
@@ -410,9 +458,9 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
                 </TableRow>
               ) : (
                 rows.map((row, idx) => {
-                  const pk = getPrimaryKey();
-                  const pkValue = pk ? row[pk.column_name] : idx;
-                  const isSelected = selectedRows.has(pkValue);
+                  const rowId = getRowId(row);
+                  const isSelected = selectedRows.has(rowId);
+                  const editable = isEditable(row);
 
                   return (
                     <React.Fragment key={idx}>
@@ -431,15 +479,13 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
                           <div className="flex justify-center">
                             <Checkbox
                               checked={isSelected}
+                              disabled={!editable}
                               onCheckedChange={() => {
                                 const newSelected = new Set(selectedRows);
-                                const currentPkValue = pk
-                                  ? row[pk.column_name]
-                                  : idx;
-                                if (newSelected.has(currentPkValue)) {
-                                  newSelected.delete(currentPkValue);
+                                if (newSelected.has(rowId)) {
+                                  newSelected.delete(rowId);
                                 } else {
-                                  newSelected.add(currentPkValue);
+                                  newSelected.add(rowId);
                                 }
                                 setSelectedRows(newSelected);
                               }}
@@ -454,6 +500,7 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
                               variant="ghost"
                               size="icon"
                               className="h-6 w-6 text-muted-foreground hover:text-foreground"
+                              disabled={!editable}
                               onClick={(e) => {
                                 e.stopPropagation();
                                 setEditRow({ ...row });
@@ -466,6 +513,7 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
                               variant="ghost"
                               size="icon"
                               className="h-6 w-6 text-muted-foreground hover:text-destructive"
+                              disabled={!editable}
                               onClick={(e) => {
                                 e.stopPropagation();
                                 setDeleteRow(row);
@@ -596,7 +644,7 @@ export function DataTable({ schema, table, onError }: DataTableProps) {
               columns.map((col) => {
                 const isPk = col.is_primary_key;
                 const hasDefault = col.column_default?.includes("nextval");
-                const disabled = isPk && !isNewRow;
+                const disabled = col.is_row_key && !isNewRow;
                 const inputType = getInputType(col.data_type);
 
                 return (
@@ -6,6 +6,7 @@ export interface Column {
   is_nullable: string;
   column_default: string | null;
   is_primary_key: boolean;
+  is_row_key: boolean;
 }
 
 export interface ApiResponse<T> {
@@ -89,7 +90,10 @@ export const api = {
         body: JSON.stringify(data),
       }
     );
-    const json: ApiResponse<Record<string, unknown>> = await res.json();
+    const json: ApiResponse<{
+      row: Record<string, unknown>;
+      rows_affected: number;
+    }> = await res.json();
 
     if (!res.ok) {
       const error = new Error(json.message || "Failed to create row");
@@ -97,14 +101,14 @@ export const api = {
       throw error;
     }
 
-    return json.data;
+    return json.data.row;
   },
 
   async updateRow(
     schema: string,
     table: string,
-    pkColumn: string,
-    pkValue: unknown,
+    key: Record<string, unknown>,
+    version: string,
     data: Record<string, unknown>
   ): Promise<void> {
     const res = await fetch(
@@ -112,7 +116,11 @@ export const api = {
       {
         method: "PATCH",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ pk_column: pkColumn, pk_value: pkValue, data }),
+        body: JSON.stringify({
+          key,
+          version,
+          data,
+        }),
       }
     );
     const json: ApiResponse<void> = await res.json();
@@ -127,15 +135,15 @@ export const api = {
   async deleteRow(
     schema: string,
     table: string,
-    pkColumn: string,
-    pkValue: unknown
+    key: Record<string, unknown>,
+    version: string
   ): Promise<void> {
     const res = await fetch(
       `${API_BASE}/rows?schema=${schema}&table=${table}`,
       {
         method: "DELETE",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ pk_column: pkColumn, pk_value: pkValue }),
+        body: JSON.stringify({ key, version }),
       }
     );
     const json: ApiResponse<void> = await res.json();